@@ -2,11 +2,16 @@
 // and feature values in a "pretty" format.
 package display
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
 
 // PrintAllImages prints the label followed by a call to PrintImage for each
 // of the labels and images in the provided slices.
-func PrintAllImages(labels []int, images [][][]int) {
+func PrintAllImages(labels []int, images []helpers.Image) {
 	for i := 0; i < len(images); i++ {
 		fmt.Println("Label:", labels[i])
 		PrintImage(images[i])
@@ -15,11 +20,11 @@ func PrintAllImages(labels []int, images [][][]int) {
 
 // PrintImage prints the provided image using whitespace X's to represent black
 // and white pixels, respectively.
-func PrintImage(image [][]int) {
-	for i := 0; i < len(image); i++ {
-		for j := 0; j < len(image[i]); j++ {
-			// fmt.Printf("%3d", image[i][j])
-			if image[i][j] == 0 {
+func PrintImage(image helpers.Image) {
+	for i := 0; i < image.Height; i++ {
+		for j := 0; j < image.Width; j++ {
+			// fmt.Printf("%3d", image.At(i, j))
+			if image.At(i, j) == 0 {
 				fmt.Print("-")
 			} else {
 				fmt.Print("X")
@@ -61,3 +66,48 @@ func PPFeatureValues(feature_values []float64) {
 	fmt.Printf("Horizontally Split Symmetry:\t%f\n", feature_values[8])
 	fmt.Println()
 }
+
+// Accuracy prints the name of a classifier followed by the relative success
+// of its predictions on the validation data.
+func Accuracy(name string, num_successes, num_errors int) {
+	success_rate := 100 * (float64(num_successes) / float64(num_successes+num_errors))
+
+	fmt.Printf("%-24s %f%% ", name+":", success_rate)
+	fmt.Printf("(%d successful, %d unsuccessful predictions)\n", num_successes, num_errors)
+}
+
+// ClassDistributions prints, for each class, the mean and standard deviation
+// of each named feature value given the per-class means and variances.
+func ClassDistributions(feature_names []string, means, variances [][]float64) {
+	fmt.Println("-----------------------------------------------------")
+	fmt.Println("Per-Class Feature Distributions (mean ± std):")
+
+	fmt.Printf("%-30s", "")
+	for class := range means {
+		fmt.Printf("%16d", class)
+	}
+	fmt.Println()
+
+	for i, name := range feature_names {
+		fmt.Printf("%-30s", name+":")
+		for class := range means {
+			cell := fmt.Sprintf("%.3f±%.3f", means[class][i], math.Sqrt(variances[class][i]))
+			fmt.Printf("%16s", cell)
+		}
+		fmt.Println()
+	}
+}
+
+// FeatureImportances prints the name of each feature followed by its
+// importance to a tree-based classifier, as a percentage. Importances beyond
+// the named features (such as that of the threshold column) are not printed.
+func FeatureImportances(feature_names []string, importances []float64) {
+	fmt.Println("-----------------------------------------------------")
+	fmt.Println("Feature Importances:")
+	for i, name := range feature_names {
+		if i >= len(importances) {
+			break
+		}
+		fmt.Printf("- %-30s %6.2f%%\n", name+":", 100*importances[i])
+	}
+}
@@ -6,25 +6,36 @@ import (
 	"fmt"
 	"os"
 
+	"project04_perceptron/go_rewrite/helpers"
 	"project04_perceptron/go_rewrite/img_manip"
 )
 
+// FeatureNames holds the name of each of the values returned by
+// GetFeatureValues, in order.
+var FeatureNames = []string{
+	"Density",
+	"Vertical Symmetry",
+	"Max Vertical Intersections",
+	"Avg Vertical Intersections",
+	"Max Horizontal Intersections",
+	"Avg Horizontal Intersections",
+	"Number of Loops",
+	"Vertically Split Symmetry",
+	"Horizontally Split Symmetry",
+}
+
 /*
 Feature 1
 
 GetDensity returns the density of the provided image.
 */
-func GetDensity(image [][]int) float64 {
-	num_rows, num_cols := len(image), len(image[0])
-
+func GetDensity(image helpers.Image) float64 {
 	sum := 0
-	for row := 0; row < num_rows; row++ {
-		for col := 0; col < num_cols; col++ {
-			sum += image[row][col]
-		}
+	for _, value := range image.Pixels {
+		sum += value
 	}
 
-	return float64(sum) / float64(num_rows*num_cols)
+	return float64(sum) / float64(image.Width*image.Height)
 }
 
 /*
@@ -38,30 +49,13 @@ For example, let I be the image and let I' be the image whose j-th column
 is the (num_columns - j)-th column of I. Then the measure of symmetry is
 the density of I XOR I'.
 */
-func GetVerticalSymmetry(image [][]int) float64 {
-	num_rows, num_cols := len(image), len(image[0])
-
-	reflected_image := make([][]int, num_rows)
-	for row := range reflected_image {
-		reflected_image[row] = make([]int, num_cols)
-	}
-
-	for row := 0; row < num_rows; row++ {
-		for col := 0; col < num_cols; col++ {
-			reflected_image[row][col] = image[row][num_cols-col-1]
-		}
-	}
-
-	xor_image := make([][]int, num_rows)
-	for row := range xor_image {
-		xor_image[row] = make([]int, num_cols)
-	}
+func GetVerticalSymmetry(image helpers.Image) float64 {
+	reflected_image := img_manip.FlipLR(image)
 
 	// bitwise XOR
-	for row := 0; row < num_rows; row++ {
-		for col := 0; col < num_cols; col++ {
-			xor_image[row][col] = image[row][col] ^ reflected_image[row][col]
-		}
+	xor_image := image.Blank(image.Depth)
+	for i := range xor_image.Pixels {
+		xor_image.Pixels[i] = image.Pixels[i] ^ reflected_image.Pixels[i]
 	}
 
 	return GetDensity(xor_image)
@@ -74,21 +68,21 @@ GetVerticalIntersections returns the maximum number of vertical
 intersections and the average number of vertical intersections of the
 provided image.
 */
-func GetVerticalIntersections(image [][]int) (int, float64) {
-	num_rows, num_cols := len(image), len(image[0])
+func GetVerticalIntersections(image helpers.Image) (int, float64) {
+	num_rows, num_cols := image.Height, image.Width
 
-	counts := make([]int, num_rows)
-	for row := 0; row < num_rows; row++ {
+	counts := make([]int, num_cols)
+	for col := 0; col < num_cols; col++ {
 		count := 0
 		prev := 0
-		for col := 0; col < num_cols; col++ {
-			current := image[col][row]
+		for row := 0; row < num_rows; row++ {
+			current := image.At(row, col)
 			if current != prev {
 				count++
 			}
 			prev = current
 		}
-		counts[row] = count
+		counts[col] = count
 	}
 
 	maximum_intersections, average_intersections := 0, 0.0
@@ -98,7 +92,7 @@ func GetVerticalIntersections(image [][]int) (int, float64) {
 		}
 		average_intersections += float64(count)
 	}
-	average_intersections /= float64(num_rows)
+	average_intersections /= float64(num_cols)
 
 	return maximum_intersections, average_intersections
 }
@@ -110,7 +104,7 @@ GetHorizontalIntersections returns the maximum number of horizontal
 intersections and the average number of horizontal intersections of the
 provided image.
 */
-func GetHorizontalIntersections(image [][]int) (int, float64) {
+func GetHorizontalIntersections(image helpers.Image) (int, float64) {
 
 	return GetVerticalIntersections(img_manip.Rotate90(image, 1))
 }
@@ -122,16 +116,14 @@ GetNumLoops returns the number of loops in the provided image by performing
 a breadth-first search on the image. The black pixels are considered unvisited
 and the white pixels are considered visited. The number of loops is the number
 of black sections that must be flood filled to turn the entire image white.
+The flood fill is performed on a copy, so the provided image is not modified.
 */
-func GetNumLoops(image [][]int) (int, error) {
-	num_rows, num_cols := len(image), len(image[0])
+func GetNumLoops(image helpers.Image) (int, error) {
+	num_rows, num_cols := image.Height, image.Width
+	image = image.Copy()
 
 	black, white := 0, 1
 
-	is_valid := func(x, y int) bool {
-		return 0 <= x && x < num_rows && 0 <= y && y < num_cols
-	}
-
 	bfs := func(x, y int) {
 		queue := [][2]int{{x, y}}
 
@@ -143,14 +135,14 @@ func GetNumLoops(image [][]int) (int, error) {
 		for len(queue) > 0 {
 			x, y := queue[0][0], queue[0][1]
 			queue = queue[1:]
-			image[x][y] = white
+			image.Set(x, y, white)
 
 			for _, dir := range directions {
 				nx, ny := x+dir[0], y+dir[1]
 
-				if is_valid(nx, ny) && image[nx][ny] == black {
+				if image.Contains(nx, ny) && image.At(nx, ny) == black {
 					queue = append(queue, [2]int{nx, ny})
-					image[nx][ny] = white
+					image.Set(nx, ny, white)
 				}
 
 			}
@@ -161,7 +153,7 @@ func GetNumLoops(image [][]int) (int, error) {
 	search_count := 0
 	for row := 0; row < num_rows; row++ {
 		for col := 0; col < num_cols; col++ {
-			if image[row][col] == black {
+			if image.At(row, col) == black {
 				bfs(row, col)
 				search_count++
 			}
@@ -178,26 +170,19 @@ GetUDSymmetry returns the degree of symmetry of the provided image
 by reflecting the top half of the image over the bottom half and
 computing the density of the bitwise XOR of the two images.
 */
-func GetUDSymmetry(image [][]int) float64 {
-	num_rows, num_cols := len(image), len(image[0])
+func GetUDSymmetry(image helpers.Image) float64 {
+	num_rows, num_cols := image.Height, image.Width
 	num_rows_split := num_rows / 2
-
-	top := image[:num_rows_split]
-	bottom, err := img_manip.FlipUD(image[num_rows_split:])
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	if num_rows_split == 0 {
+		return 0
 	}
 
-	xor_image := make([][]int, num_rows_split)
-	for row := range xor_image {
-		xor_image[row] = make([]int, num_cols)
-	}
+	top := img_manip.Crop(image, 0, 0, num_rows_split-1, num_cols-1)
+	bottom := img_manip.FlipUD(img_manip.Crop(image, num_rows-num_rows_split, 0, num_rows-1, num_cols-1))
 
-	for row := 0; row < num_rows_split; row++ {
-		for col := 0; col < num_cols; col++ {
-			xor_image[row][col] = top[row][col] ^ bottom[row][col]
-		}
+	xor_image := top.Blank(top.Depth)
+	for i := range xor_image.Pixels {
+		xor_image.Pixels[i] = top.Pixels[i] ^ bottom.Pixels[i]
 	}
 
 	return GetDensity(xor_image)
@@ -211,7 +196,7 @@ by rotating the image 90 degrees clockwise and calling GetUDSymmetry
 as if to reflect the left half of the image over the right half and
 compute the density of the bitwise XOR of the two images.
 */
-func GetLRSymmetry(image [][]int) float64 {
+func GetLRSymmetry(image helpers.Image) float64 {
 
 	return GetUDSymmetry(img_manip.Rotate90(image, 1))
 }
@@ -221,7 +206,7 @@ Feature 8
 
 GetFeatureValues returns the feature values of the provided image.
 */
-func GetFeatureValues(image [][]int) []float64 {
+func GetFeatureValues(image helpers.Image) []float64 {
 	num_features := 9
 	feature_values := make([]float64, num_features)
 
@@ -257,3 +242,14 @@ func GetFeatureValues(image [][]int) []float64 {
 
 	return feature_values
 }
+
+// GetPixelValues returns the pixel values of the provided greyscale image,
+// row by row, scaled from [0, 255] to [0, 1] so that they can be used as raw
+// feature values.
+func GetPixelValues(image helpers.Image) []float64 {
+	pixel_values := make([]float64, len(image.Pixels))
+	for i, value := range image.Pixels {
+		pixel_values[i] = float64(value) / 255
+	}
+	return pixel_values
+}
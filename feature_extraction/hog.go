@@ -0,0 +1,159 @@
+package feature_extraction
+
+import (
+	"fmt"
+	"math"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// HOGNorm names the normalization applied to each block of a HOG descriptor.
+type HOGNorm string
+
+const (
+	L1     HOGNorm = "L1"
+	L1Sqrt HOGNorm = "L1-sqrt"
+	L2     HOGNorm = "L2"
+	L2Hys  HOGNorm = "L2-Hys"
+)
+
+func init() {
+	Register("hog", NewHOGExtractor(28, 28, 7, 2, 9, L2Hys))
+}
+
+// GetHOG returns the Histogram of Oriented Gradients descriptor of the provided
+// greyscale image. The gradient of each pixel is computed with centred
+// differences; the image is divided into square cells of cell_size pixels, and
+// each cell gets a histogram of num_bins unsigned orientations (0 to 180
+// degrees) weighted by gradient magnitude. Overlapping square blocks of
+// block_size cells, one cell apart, are normalized with norm and concatenated.
+// Pixels beyond the last whole cell are ignored.
+func GetHOG(image helpers.Image, cell_size, block_size, num_bins int, norm HOGNorm) []float64 {
+	num_rows, num_cols := image.Height, image.Width
+	cells_down, cells_across := num_rows/cell_size, num_cols/cell_size
+
+	pixel := func(row, col int) float64 {
+		row = min(max(row, 0), num_rows-1)
+		col = min(max(col, 0), num_cols-1)
+		return float64(image.At(row, col))
+	}
+
+	histograms := make([][][]float64, cells_down)
+	for cell_row := range histograms {
+		histograms[cell_row] = make([][]float64, cells_across)
+		for cell_col := range histograms[cell_row] {
+			histograms[cell_row][cell_col] = make([]float64, num_bins)
+		}
+	}
+
+	bin_width := math.Pi / float64(num_bins)
+	for row := 0; row < cells_down*cell_size; row++ {
+		for col := 0; col < cells_across*cell_size; col++ {
+			dx := pixel(row, col+1) - pixel(row, col-1)
+			dy := pixel(row+1, col) - pixel(row-1, col)
+
+			magnitude := math.Hypot(dx, dy)
+			if magnitude == 0 {
+				continue
+			}
+
+			angle := math.Atan2(dy, dx)
+			if angle < 0 {
+				angle += math.Pi
+			}
+
+			// Share the magnitude between the two nearest bin centres.
+			position := angle/bin_width - 0.5
+			lower := int(math.Floor(position))
+			fraction := position - float64(lower)
+
+			histogram := histograms[row/cell_size][col/cell_size]
+			histogram[(lower+num_bins)%num_bins] += magnitude * (1 - fraction)
+			histogram[(lower+1)%num_bins] += magnitude * fraction
+		}
+	}
+
+	descriptor := []float64{}
+	for block_row := 0; block_row+block_size <= cells_down; block_row++ {
+		for block_col := 0; block_col+block_size <= cells_across; block_col++ {
+			block := make([]float64, 0, block_size*block_size*num_bins)
+			for cell_row := block_row; cell_row < block_row+block_size; cell_row++ {
+				for cell_col := block_col; cell_col < block_col+block_size; cell_col++ {
+					block = append(block, histograms[cell_row][cell_col]...)
+				}
+			}
+			descriptor = append(descriptor, normalizeBlock(block, norm)...)
+		}
+	}
+
+	return descriptor
+}
+
+// normalizeBlock returns the provided HOG block normalized with the provided
+// norm.
+func normalizeBlock(block []float64, norm HOGNorm) []float64 {
+	epsilon := 1e-6
+
+	l1, l2 := func() float64 {
+		sum := 0.0
+		for _, value := range block {
+			sum += math.Abs(value)
+		}
+		return sum + epsilon
+	}, func() float64 {
+		sum := 0.0
+		for _, value := range block {
+			sum += value * value
+		}
+		return math.Sqrt(sum + epsilon*epsilon)
+	}
+
+	normalized := make([]float64, len(block))
+	switch norm {
+	case L1, L1Sqrt:
+		total := l1()
+		for i, value := range block {
+			normalized[i] = value / total
+			if norm == L1Sqrt {
+				normalized[i] = math.Sqrt(normalized[i])
+			}
+		}
+	case L2Hys:
+		total := l2()
+		for i, value := range block {
+			normalized[i] = math.Min(value/total, 0.2)
+		}
+		return normalizeBlock(normalized, L2)
+	default:
+		total := l2()
+		for i, value := range block {
+			normalized[i] = value / total
+		}
+	}
+
+	return normalized
+}
+
+// NewHOGExtractor returns an extractor of the HOG descriptor of num_rows by
+// num_cols greyscale images with the provided parameters.
+func NewHOGExtractor(num_rows, num_cols, cell_size, block_size, num_bins int, norm HOGNorm) Extractor {
+	blocks_down := max(num_rows/cell_size-block_size+1, 0)
+	blocks_across := max(num_cols/cell_size-block_size+1, 0)
+
+	names := []string{}
+	for block := 0; block < blocks_down*blocks_across; block++ {
+		for cell := 0; cell < block_size*block_size; cell++ {
+			for bin := 0; bin < num_bins; bin++ {
+				names = append(names, fmt.Sprintf("HOG Block %d Cell %d Bin %d", block, cell, bin))
+			}
+		}
+	}
+
+	return Extractor{
+		Names: names,
+		Extract: func(image helpers.Image) []float64 {
+			return GetHOG(image, cell_size, block_size, num_bins, norm)
+		},
+		Greyscale: true,
+	}
+}
@@ -0,0 +1,147 @@
+package feature_extraction
+
+import (
+	"fmt"
+	"math"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+func init() {
+	names := []string{"Centroid Row", "Centroid Column", "Orientation", "Eccentricity"}
+	for i := 1; i <= 7; i++ {
+		names = append(names, fmt.Sprintf("Hu Moment %d", i))
+	}
+
+	Register("moments", Extractor{
+		Names: names,
+		Extract: func(image helpers.Image) []float64 {
+			num_rows, num_cols := image.Height, image.Width
+			centroid_row, centroid_col := GetCentroid(image)
+
+			feature_values := []float64{
+				centroid_row / float64(num_rows),
+				centroid_col / float64(num_cols),
+				GetOrientation(image),
+				GetEccentricity(image),
+			}
+
+			// The Hu moments span many orders of magnitude, so they are
+			// compared on a signed log scale.
+			for _, hu := range GetHuMoments(image) {
+				if hu != 0 {
+					hu = -math.Copysign(math.Log10(math.Abs(hu)), hu)
+				}
+				feature_values = append(feature_values, hu)
+			}
+
+			return feature_values
+		},
+	})
+}
+
+// GetRawMoment returns the raw (geometric) moment M_pq of the provided image,
+// the sum over every pixel of x^p * y^q * I(x, y), where x is the column and y
+// the row of the pixel and I(x, y) its value.
+func GetRawMoment(image helpers.Image, p, q int) float64 {
+	moment := 0.0
+	for row := 0; row < image.Height; row++ {
+		for col := 0; col < image.Width; col++ {
+			if value := image.At(row, col); value != 0 {
+				moment += math.Pow(float64(col), float64(p)) * math.Pow(float64(row), float64(q)) * float64(value)
+			}
+		}
+	}
+	return moment
+}
+
+// GetCentroid returns the row and column of the centre of mass of the provided
+// image. The centroid of an image with no ink is its geometric centre.
+func GetCentroid(image helpers.Image) (float64, float64) {
+	m00 := GetRawMoment(image, 0, 0)
+	if m00 == 0 {
+		return float64(image.Height-1) / 2, float64(image.Width-1) / 2
+	}
+	return GetRawMoment(image, 0, 1) / m00, GetRawMoment(image, 1, 0) / m00
+}
+
+// GetCentralMoment returns the central moment mu_pq of the provided image: the
+// raw moment taken about the centroid, which makes it invariant to translation.
+func GetCentralMoment(image helpers.Image, p, q int) float64 {
+	centroid_row, centroid_col := GetCentroid(image)
+
+	moment := 0.0
+	for row := 0; row < image.Height; row++ {
+		for col := 0; col < image.Width; col++ {
+			if value := image.At(row, col); value != 0 {
+				x, y := float64(col)-centroid_col, float64(row)-centroid_row
+				moment += math.Pow(x, float64(p)) * math.Pow(y, float64(q)) * float64(value)
+			}
+		}
+	}
+	return moment
+}
+
+// GetNormalizedCentralMoment returns the normalized central moment eta_pq of
+// the provided image, mu_pq / mu_00^(1 + (p+q)/2), which is invariant to both
+// translation and scale. An image with no ink has all moments equal to zero.
+func GetNormalizedCentralMoment(image helpers.Image, p, q int) float64 {
+	mu00 := GetRawMoment(image, 0, 0)
+	if mu00 == 0 {
+		return 0
+	}
+	return GetCentralMoment(image, p, q) / math.Pow(mu00, 1+float64(p+q)/2)
+}
+
+// GetHuMoments returns the seven Hu moment invariants of the provided image,
+// which are invariant to translation, scale and rotation (the seventh changes
+// sign under reflection).
+func GetHuMoments(image helpers.Image) [7]float64 {
+	n20 := GetNormalizedCentralMoment(image, 2, 0)
+	n02 := GetNormalizedCentralMoment(image, 0, 2)
+	n11 := GetNormalizedCentralMoment(image, 1, 1)
+	n30 := GetNormalizedCentralMoment(image, 3, 0)
+	n03 := GetNormalizedCentralMoment(image, 0, 3)
+	n21 := GetNormalizedCentralMoment(image, 2, 1)
+	n12 := GetNormalizedCentralMoment(image, 1, 2)
+
+	a, b := n30+n12, n21+n03
+
+	return [7]float64{
+		n20 + n02,
+		(n20-n02)*(n20-n02) + 4*n11*n11,
+		(n30-3*n12)*(n30-3*n12) + (3*n21-n03)*(3*n21-n03),
+		a*a + b*b,
+		(n30-3*n12)*a*(a*a-3*b*b) + (3*n21-n03)*b*(3*a*a-b*b),
+		(n20-n02)*(a*a-b*b) + 4*n11*a*b,
+		(3*n21-n03)*a*(a*a-3*b*b) - (n30-3*n12)*b*(3*a*a-b*b),
+	}
+}
+
+// GetOrientation returns the angle, in radians between -pi/2 and pi/2, between
+// the principal (major) axis of the provided image and its rows. Since rows are
+// numbered downwards, a positive angle means the axis slopes down to the right.
+func GetOrientation(image helpers.Image) float64 {
+	mu11 := GetCentralMoment(image, 1, 1)
+	mu20 := GetCentralMoment(image, 2, 0)
+	mu02 := GetCentralMoment(image, 0, 2)
+
+	return 0.5 * math.Atan2(2*mu11, mu20-mu02)
+}
+
+// GetEccentricity returns the eccentricity of the ellipse with the same second
+// moments as the provided image, from 0 for a circle to 1 for a line.
+func GetEccentricity(image helpers.Image) float64 {
+	mu11 := GetCentralMoment(image, 1, 1)
+	mu20 := GetCentralMoment(image, 2, 0)
+	mu02 := GetCentralMoment(image, 0, 2)
+
+	// Eigenvalues of the covariance matrix [[mu20 mu11] [mu11 mu02]].
+	spread := math.Sqrt((mu20-mu02)*(mu20-mu02) + 4*mu11*mu11)
+	major, minor := (mu20+mu02+spread)/2, (mu20+mu02-spread)/2
+	if major <= 0 {
+		return 0
+	}
+
+	return math.Sqrt(1 - math.Max(minor, 0)/major)
+}
@@ -0,0 +1,127 @@
+package feature_extraction
+
+import (
+	"fmt"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+func init() {
+	Register("projection_28", NewProjectionExtractor(28))
+	Register("projection_7", NewProjectionExtractor(7))
+	Register("projection_stats", Extractor{
+		Names: []string{
+			"Row Projection Peak",
+			"Row Projection Mean",
+			"Row Projection Variance",
+			"Column Projection Peak",
+			"Column Projection Mean",
+			"Column Projection Variance",
+		},
+		Extract: func(image helpers.Image) []float64 {
+			row_peak, row_mean, row_variance := GetProjectionStatistics(GetRowProjection(image))
+			col_peak, col_mean, col_variance := GetProjectionStatistics(GetColumnProjection(image))
+			return []float64{row_peak, row_mean, row_variance, col_peak, col_mean, col_variance}
+		},
+	})
+}
+
+// GetRowProjection returns the row projection histogram of the provided image:
+// the number of white (ink) pixels in each row.
+func GetRowProjection(image helpers.Image) []int {
+	projection := make([]int, image.Height)
+	for row := 0; row < image.Height; row++ {
+		for col := 0; col < image.Width; col++ {
+			projection[row] += image.At(row, col)
+		}
+	}
+	return projection
+}
+
+// GetColumnProjection returns the column projection histogram of the provided
+// image: the number of white (ink) pixels in each column.
+func GetColumnProjection(image helpers.Image) []int {
+	projection := make([]int, image.Width)
+	for row := 0; row < image.Height; row++ {
+		for col := 0; col < image.Width; col++ {
+			projection[col] += image.At(row, col)
+		}
+	}
+	return projection
+}
+
+// GetBucketedProjection groups the provided projection histogram into
+// num_buckets buckets of consecutive lines and returns the density of each
+// bucket, that is, the number of ink pixels in the bucket divided by the number
+// of pixels in it. line_length is the number of pixels in each line of the
+// projection (the number of columns for a row projection and vice versa).
+func GetBucketedProjection(projection []int, line_length, num_buckets int) []float64 {
+	densities := make([]float64, num_buckets)
+	for bucket := range densities {
+		start, end := bucket*len(projection)/num_buckets, (bucket+1)*len(projection)/num_buckets
+		if start == end {
+			continue
+		}
+
+		sum := 0
+		for _, count := range projection[start:end] {
+			sum += count
+		}
+		densities[bucket] = float64(sum) / float64((end-start)*line_length)
+	}
+	return densities
+}
+
+// GetProjectionStatistics returns the position of the peak of the provided
+// projection histogram, and the mean and variance of the position of its ink.
+// Positions are scaled to [0, 1) by the length of the projection, so the
+// statistics do not depend on the size of the image. A projection with no ink
+// has all three statistics equal to zero.
+func GetProjectionStatistics(projection []int) (float64, float64, float64) {
+	length := float64(len(projection))
+
+	peak, total, sum := 0, 0, 0.0
+	for position, count := range projection {
+		if count > projection[peak] {
+			peak = position
+		}
+		total += count
+		sum += float64(position*count) / length
+	}
+	if total == 0 {
+		return 0, 0, 0
+	}
+
+	mean := sum / float64(total)
+
+	variance := 0.0
+	for position, count := range projection {
+		difference := float64(position)/length - mean
+		variance += float64(count) * difference * difference
+	}
+	variance /= float64(total)
+
+	return float64(peak) / length, mean, variance
+}
+
+// NewProjectionExtractor returns an extractor of the row and column projection
+// histograms of an image, each grouped into num_buckets buckets.
+func NewProjectionExtractor(num_buckets int) Extractor {
+	names := make([]string, 0, 2*num_buckets)
+	for bucket := 0; bucket < num_buckets; bucket++ {
+		names = append(names, fmt.Sprintf("Row Bucket %d Density", bucket))
+	}
+	for bucket := 0; bucket < num_buckets; bucket++ {
+		names = append(names, fmt.Sprintf("Column Bucket %d Density", bucket))
+	}
+
+	return Extractor{
+		Names: names,
+		Extract: func(image helpers.Image) []float64 {
+			num_rows, num_cols := image.Height, image.Width
+			rows := GetBucketedProjection(GetRowProjection(image), num_cols, num_buckets)
+			cols := GetBucketedProjection(GetColumnProjection(image), num_rows, num_buckets)
+			return append(rows, cols...)
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package feature_extraction
+
+import (
+	"fmt"
+	"sort"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// Extractor computes a fixed number of named feature values from an image.
+// Extract must return exactly len(Names) values. Extract is given the binary
+// image unless Greyscale is true, in which case it is given the original
+// greyscale image.
+type Extractor struct {
+	Names     []string
+	Extract   func(image helpers.Image) []float64
+	Greyscale bool
+}
+
+// FeatureVersion identifies the definitions of the registered extractors. It
+// must be increased whenever a change makes an extractor return different
+// values for the same image, so that feature values cached on disk by earlier
+// versions are recomputed rather than reused.
+const FeatureVersion = 1
+
+var extractors = map[string]Extractor{}
+
+func init() {
+	Register("basic", Extractor{Names: FeatureNames, Extract: GetFeatureValues})
+}
+
+// Register makes the provided extractor available under the provided name so
+// that it can be selected as part of a feature set. Registering a name twice
+// replaces the earlier extractor.
+func Register(name string, extractor Extractor) {
+	extractors[name] = extractor
+}
+
+// GetExtractor returns the extractor registered under the provided name.
+func GetExtractor(name string) (Extractor, error) {
+	extractor, ok := extractors[name]
+	if !ok {
+		return Extractor{}, fmt.Errorf("no feature extractor registered as %q", name)
+	}
+	return extractor, nil
+}
+
+// GetExtractorNames returns the names of every registered extractor, sorted.
+func GetExtractorNames() []string {
+	names := make([]string, 0, len(extractors))
+	for name := range extractors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetFeatureSetNames returns the names of the feature values produced by the
+// provided feature set, which is a list of registered extractor names.
+func GetFeatureSetNames(feature_set []string) ([]string, error) {
+	names := []string{}
+	for _, extractor_name := range feature_set {
+		extractor, err := GetExtractor(extractor_name)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, extractor.Names...)
+	}
+	return names, nil
+}
+
+// GetFeatureSetValues returns the feature values of an image for the provided
+// feature set, which is a list of registered extractor names, given both the
+// greyscale image and its binary (black and white) version. Extractors must
+// not modify the image they are given, so that their values do not depend on
+// the order in which they are applied.
+func GetFeatureSetValues(greyscale_image, binary_image helpers.Image, feature_set []string) ([]float64, error) {
+	feature_values := []float64{}
+	for _, extractor_name := range feature_set {
+		extractor, err := GetExtractor(extractor_name)
+		if err != nil {
+			return nil, err
+		}
+
+		image := binary_image
+		if extractor.Greyscale {
+			image = greyscale_image
+		}
+
+		values := extractor.Extract(image)
+		if len(values) != len(extractor.Names) {
+			return nil, fmt.Errorf("feature extractor %q returned %d values, expected %d",
+				extractor_name, len(values), len(extractor.Names))
+		}
+		feature_values = append(feature_values, values...)
+	}
+	return feature_values, nil
+}
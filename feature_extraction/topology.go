@@ -0,0 +1,187 @@
+package feature_extraction
+
+import (
+	"project04_perceptron/go_rewrite/helpers"
+	"project04_perceptron/go_rewrite/img_manip"
+)
+
+func init() {
+	Register("topology", Extractor{
+		Names: []string{
+			"Euler Number",
+			"Number of Holes",
+			"Holes in Upper Third",
+			"Holes in Middle Third",
+			"Holes in Lower Third",
+			"Ink Components",
+			"Skeleton Endpoints",
+			"Skeleton Junctions",
+		},
+		Extract: func(image helpers.Image) []float64 {
+			holes := GetHoles(image)
+			upper, middle, lower := GetHolePlacement(image, holes)
+
+			endpoints, junctions := GetSkeletonEndpointsAndJunctions(image)
+
+			return []float64{
+				float64(GetEulerNumber(image)),
+				float64(len(holes)),
+				float64(upper),
+				float64(middle),
+				float64(lower),
+				float64(GetInkComponents(image)),
+				float64(endpoints),
+				float64(junctions),
+			}
+		},
+	})
+}
+
+// Hole is a region of background enclosed by ink, described by the row and
+// column of its centroid and its area in pixels.
+type Hole struct {
+	Row, Col float64
+	Area     int
+}
+
+// GetInkComponents returns the number of 8-connected components of ink (white)
+// pixels in the provided binary image.
+func GetInkComponents(image helpers.Image) int {
+	return len(getComponents(image, 1, true))
+}
+
+// GetHoles returns the holes of the provided binary image: the 4-connected
+// components of background (black) pixels that do not touch the border of the
+// image. Background is 4-connected so that ink is 8-connected, which means that
+// a diagonal gap between two ink pixels does not open a hole.
+func GetHoles(image helpers.Image) []Hole {
+	num_rows, num_cols := image.Height, image.Width
+
+	holes := []Hole{}
+	for _, component := range getComponents(image, 0, false) {
+		touches_border := false
+		row_sum, col_sum := 0, 0
+		for _, pixel := range component {
+			row, col := pixel[0], pixel[1]
+			if row == 0 || col == 0 || row == num_rows-1 || col == num_cols-1 {
+				touches_border = true
+				break
+			}
+			row_sum += row
+			col_sum += col
+		}
+
+		if !touches_border {
+			holes = append(holes, Hole{
+				Row:  float64(row_sum) / float64(len(component)),
+				Col:  float64(col_sum) / float64(len(component)),
+				Area: len(component),
+			})
+		}
+	}
+
+	return holes
+}
+
+// GetHolePlacement returns the number of the provided holes of the provided
+// binary image whose centroid lies in the upper, middle and lower third of the
+// rows spanned by its ink. Measuring against the ink rather than the image
+// keeps the placement of a small or off-centre digit's holes in step with a
+// large, centred one: the hole of a 9 is upper, that of a 0 middle and that of
+// a 6 lower.
+func GetHolePlacement(image helpers.Image, holes []Hole) (int, int, int) {
+	top, bottom := -1, -1
+	for row, count := range GetRowProjection(image) {
+		if count > 0 {
+			if top < 0 {
+				top = row
+			}
+			bottom = row
+		}
+	}
+
+	upper, middle, lower := 0, 0, 0
+	for _, hole := range holes {
+		position := (hole.Row - float64(top)) / float64(bottom-top)
+		switch {
+		case position < 1.0/3:
+			upper++
+		case position > 2.0/3:
+			lower++
+		default:
+			middle++
+		}
+	}
+	return upper, middle, lower
+}
+
+// GetEulerNumber returns the Euler number of the provided binary image: the
+// number of ink components minus the number of holes. An 8 has Euler number -1,
+// a 0, 6 or 9 has 0, and a 1 has 1.
+func GetEulerNumber(image helpers.Image) int {
+	return GetInkComponents(image) - len(GetHoles(image))
+}
+
+// GetSkeletonEndpointsAndJunctions returns the number of endpoints and the
+// number of junctions of the skeleton of the provided binary image. An endpoint
+// is a skeleton pixel with exactly one skeleton neighbour. A junction is a
+// place where three or more branches meet, counting touching branch points as
+// a single junction.
+func GetSkeletonEndpointsAndJunctions(image helpers.Image) (int, int) {
+	skeleton := img_manip.ZhangSuenThin(image)
+
+	branch_points := skeleton.Blank(helpers.Binary)
+	for _, pixel := range img_manip.GetBranchPoints(skeleton) {
+		branch_points.Set(pixel[0], pixel[1], 1)
+	}
+
+	return len(img_manip.GetEndPoints(skeleton)), len(getComponents(branch_points, 1, true))
+}
+
+// getComponents returns the connected components of the pixels of the
+// provided image equal to value, each as a list of (row, column) pairs, using
+// 8-connectivity if eight_connected is true and 4-connectivity otherwise.
+func getComponents(image helpers.Image, value int, eight_connected bool) [][][2]int {
+	num_rows, num_cols := image.Height, image.Width
+
+	directions := [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	if eight_connected {
+		directions = append(directions, [2]int{1, 1}, [2]int{1, -1}, [2]int{-1, 1}, [2]int{-1, -1})
+	}
+
+	visited := make([][]bool, num_rows)
+	for row := range visited {
+		visited[row] = make([]bool, num_cols)
+	}
+
+	components := [][][2]int{}
+	for row := 0; row < num_rows; row++ {
+		for col := 0; col < num_cols; col++ {
+			if visited[row][col] || image.At(row, col) != value {
+				continue
+			}
+
+			component := [][2]int{}
+			queue := [][2]int{{row, col}}
+			visited[row][col] = true
+
+			for len(queue) > 0 {
+				pixel := queue[0]
+				queue = queue[1:]
+				component = append(component, pixel)
+
+				for _, dir := range directions {
+					nx, ny := pixel[0]+dir[0], pixel[1]+dir[1]
+					if image.Contains(nx, ny) && !visited[nx][ny] && image.At(nx, ny) == value {
+						visited[nx][ny] = true
+						queue = append(queue, [2]int{nx, ny})
+					}
+				}
+			}
+
+			components = append(components, component)
+		}
+	}
+
+	return components
+}
@@ -0,0 +1,62 @@
+package feature_extraction
+
+import (
+	"fmt"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+func init() {
+	Register("zoning_4x4", NewZoningExtractor(4, 4))
+	Register("zoning_7x7", NewZoningExtractor(7, 7))
+}
+
+// GetZoningDensities splits the provided image into a grid of grid_rows by
+// grid_cols cells and returns the density of each cell, row by row. When the
+// image does not divide evenly, the cells differ in size by at most one pixel
+// along each axis.
+func GetZoningDensities(image helpers.Image, grid_rows, grid_cols int) []float64 {
+	num_rows, num_cols := image.Height, image.Width
+
+	densities := make([]float64, 0, grid_rows*grid_cols)
+	for cell_row := 0; cell_row < grid_rows; cell_row++ {
+		row_start, row_end := cell_row*num_rows/grid_rows, (cell_row+1)*num_rows/grid_rows
+
+		for cell_col := 0; cell_col < grid_cols; cell_col++ {
+			col_start, col_end := cell_col*num_cols/grid_cols, (cell_col+1)*num_cols/grid_cols
+
+			sum, area := 0, (row_end-row_start)*(col_end-col_start)
+			for row := row_start; row < row_end; row++ {
+				for col := col_start; col < col_end; col++ {
+					sum += image.At(row, col)
+				}
+			}
+
+			density := 0.0
+			if area > 0 {
+				density = float64(sum) / float64(area)
+			}
+			densities = append(densities, density)
+		}
+	}
+
+	return densities
+}
+
+// NewZoningExtractor returns an extractor of the grid_rows by grid_cols zoning
+// densities of an image, named after the row and column of each cell.
+func NewZoningExtractor(grid_rows, grid_cols int) Extractor {
+	names := make([]string, 0, grid_rows*grid_cols)
+	for row := 0; row < grid_rows; row++ {
+		for col := 0; col < grid_cols; col++ {
+			names = append(names, fmt.Sprintf("Zone (%d,%d) Density", row, col))
+		}
+	}
+
+	return Extractor{
+		Names: names,
+		Extract: func(image helpers.Image) []float64 {
+			return GetZoningDensities(image, grid_rows, grid_cols)
+		},
+	}
+}
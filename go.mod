@@ -1,3 +1,3 @@
 module project04_perceptron/go_rewrite
 
-go 1.21.1
+go 1.24
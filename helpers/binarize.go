@@ -0,0 +1,158 @@
+package helpers
+
+import (
+	"fmt"
+	"math"
+)
+
+// BinarizationMethod names a way of choosing the threshold that separates ink
+// from background.
+type BinarizationMethod string
+
+const (
+	Fixed   BinarizationMethod = "fixed"   // the same threshold for every image
+	Otsu    BinarizationMethod = "otsu"    // a global threshold per image, by Otsu's method
+	Mean    BinarizationMethod = "mean"    // a global threshold per image, at its mean value
+	Niblack BinarizationMethod = "niblack" // a local threshold per pixel, by Niblack's method
+	Sauvola BinarizationMethod = "sauvola" // a local threshold per pixel, by Sauvola's method
+)
+
+// Binarization describes how a greyscale image is converted to black and
+// white. Threshold is used by the Fixed method; Window and K by the local
+// methods, where Window is the odd side length of the square neighbourhood
+// examined around each pixel.
+type Binarization struct {
+	Method    BinarizationMethod
+	Threshold int     `json:",omitempty"`
+	Window    int     `json:",omitempty"`
+	K         float64 `json:",omitempty"`
+}
+
+// Binarize returns a black and white copy of the provided greyscale image,
+// with 1 for ink and 0 for background, using the provided binarization. The
+// provided image is not modified.
+func Binarize(image Image, binarization Binarization) (Image, error) {
+	switch binarization.Method {
+	case Fixed:
+		return GetBlackWhite(image, binarization.Threshold), nil
+	case Otsu:
+		return GetBlackWhite(image, GetOtsuThreshold(image)), nil
+	case Mean:
+		return GetBlackWhite(image, GetMeanThreshold(image)), nil
+	case Niblack, Sauvola:
+		if binarization.Window < 1 || binarization.Window%2 == 0 {
+			return Image{}, fmt.Errorf("binarization window must be odd and positive, got %d", binarization.Window)
+		}
+		return GetLocalBlackWhite(image, binarization.Method, binarization.Window, binarization.K), nil
+	default:
+		return Image{}, fmt.Errorf("unknown binarization method %q", binarization.Method)
+	}
+}
+
+// GetOtsuThreshold returns the threshold for the provided greyscale image that
+// maximizes the variance between the pixels below it and those at or above it
+// (Otsu's method). Pixel values are clamped to [0, 255].
+func GetOtsuThreshold(image Image) int {
+	histogram := make([]float64, 256)
+	total := 0.0
+	for _, value := range image.Pixels {
+		histogram[min(max(value, 0), 255)]++
+		total++
+	}
+
+	sum := 0.0
+	for value, count := range histogram {
+		sum += float64(value) * count
+	}
+
+	best_threshold, best_variance := 128, -1.0
+	below_count, below_sum := 0.0, 0.0
+	for threshold := 1; threshold < 256; threshold++ {
+		below_count += histogram[threshold-1]
+		below_sum += float64(threshold-1) * histogram[threshold-1]
+
+		above_count := total - below_count
+		if below_count == 0 || above_count == 0 {
+			continue
+		}
+
+		below_mean, above_mean := below_sum/below_count, (sum-below_sum)/above_count
+		variance := below_count * above_count * (below_mean - above_mean) * (below_mean - above_mean)
+		if variance > best_variance {
+			best_threshold, best_variance = threshold, variance
+		}
+	}
+
+	return best_threshold
+}
+
+// GetMeanThreshold returns the mean pixel value of the provided greyscale
+// image, rounded up so that a blank image is entirely background. An image
+// with no pixels has the default threshold of 128, like GetOtsuThreshold.
+func GetMeanThreshold(image Image) int {
+	if len(image.Pixels) == 0 {
+		return 128
+	}
+
+	sum := 0
+	for _, value := range image.Pixels {
+		sum += value
+	}
+	return sum/len(image.Pixels) + 1
+}
+
+// GetLocalBlackWhite returns a black and white copy of the provided greyscale
+// image in which each pixel is compared with a threshold computed from the
+// mean m and standard deviation s of the window by window neighbourhood
+// around it, so that faint strokes on a faint scan are kept and dark smudges
+// on a dark scan are not.
+//
+// Both methods were designed for dark ink on light paper, so they are applied
+// to the inverted image, where a pixel is ink if it is below the threshold:
+// m + k*s for Niblack (k is typically -0.2) and m * (1 + k*(s/128 - 1)) for
+// Sauvola (k is typically 0.2 to 0.5).
+func GetLocalBlackWhite(image Image, method BinarizationMethod, window int, k float64) Image {
+	num_rows, num_cols := image.Height, image.Width
+	radius := window / 2
+
+	// Summed-area tables of the inverted image and its square give the mean
+	// and variance of any window in constant time.
+	sums := make([][]float64, num_rows+1)
+	squares := make([][]float64, num_rows+1)
+	for row := range sums {
+		sums[row] = make([]float64, num_cols+1)
+		squares[row] = make([]float64, num_cols+1)
+	}
+	for row := 0; row < num_rows; row++ {
+		for col := 0; col < num_cols; col++ {
+			value := float64(255 - image.At(row, col))
+			sums[row+1][col+1] = value + sums[row][col+1] + sums[row+1][col] - sums[row][col]
+			squares[row+1][col+1] = value*value + squares[row][col+1] + squares[row+1][col] - squares[row][col]
+		}
+	}
+
+	binary := image.Blank(Binary)
+	for row := 0; row < num_rows; row++ {
+		for col := 0; col < num_cols; col++ {
+			top, bottom := max(row-radius, 0), min(row+radius+1, num_rows)
+			left, right := max(col-radius, 0), min(col+radius+1, num_cols)
+			area := float64((bottom - top) * (right - left))
+
+			sum := sums[bottom][right] - sums[top][right] - sums[bottom][left] + sums[top][left]
+			square := squares[bottom][right] - squares[top][right] - squares[bottom][left] + squares[top][left]
+			mean := sum / area
+			deviation := math.Sqrt(math.Max(square/area-mean*mean, 0))
+
+			threshold := mean + k*deviation
+			if method == Sauvola {
+				threshold = mean * (1 + k*(deviation/128-1))
+			}
+
+			if float64(255-image.At(row, col)) < threshold {
+				binary.Set(row, col, 1)
+			}
+		}
+	}
+
+	return binary
+}
@@ -3,11 +3,9 @@ package helpers
 
 import (
 	"bufio"
-	"encoding/csv"
 	"math/rand"
 	"os"
 	"strconv"
-	"strings"
 )
 
 // DotProduct returns the dot product of the provided vectors.
@@ -24,8 +22,13 @@ func DotProduct(a, b []float64) float64 {
 	return result
 }
 
-// ArgMax returns the index of the maximum value in the provided slice.
+// ArgMax returns the index of the maximum value in the provided slice, or -1
+// if the slice is empty.
 func ArgMax(a []float64) int {
+	if len(a) == 0 {
+		return -1
+	}
+
 	max_value, max_index := a[0], 0
 
 	for i := range a {
@@ -87,88 +90,31 @@ func Multiply(vector []float64, scalar float64) []float64 {
 	return result
 }
 
-// ExtractImages reads the provided CSV file and returns a slice of images
-// and a slice of labels if has_label is true. If has_label is false, the
-// returned slice of labels will be nil.
-func ExtractImages(file string, has_label bool) ([][][]int, []int, error) {
-	// Open the CSV file
-	csv_file, err := os.Open(file)
-	if err != nil {
-		return nil, nil, err
-	}
-	defer csv_file.Close()
-
-	// Create a CSV reader
-	reader := csv.NewReader(csv_file)
-
-	first_line, err := reader.Read()
-	if err != nil {
-		return nil, nil, err
-	}
-
-	first_line[0] = strings.TrimPrefix(first_line[0], "\ufeff")
-
-	// Read all CSV records
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Initialize slices for images and labels
-	var images [][][]int
-	var labels []int
-
-	// Determine the starting column index based on whether the CSV has labels
-	start_col := 0
-	if has_label {
-		start_col = 1
-	}
-
-	// Iterate over each row in the CSV
-	for _, record := range records {
-		// Parse the label if it exists
-		if has_label {
-			label, err := strconv.Atoi(record[0])
-			if err != nil {
-				return nil, nil, err
-			}
-			labels = append(labels, label)
-		}
-
-		// Parse the pixel values and convert to a 28x28 image
-		image := make([][]int, 28)
-		for i := 0; i < 28; i++ {
-			image[i] = make([]int, 28)
-			for j := 0; j < 28; j++ {
-				pixelValue, err := strconv.Atoi(record[start_col+i*28+j])
-				if err != nil {
-					return nil, nil, err
-				}
-				image[i][j] = pixelValue
-			}
-		}
-		images = append(images, image)
-	}
-
-	return images, labels, nil
+// ExtractImages reads the provided CSV file of 28x28 greyscale images and
+// returns a slice of images and a slice of labels if has_label is true. If
+// has_label is false, the returned slice of labels will be nil. Use an
+// ImageReader to process a large file one image at a time instead, and a
+// CSVSchema to read files of another layout.
+func ExtractImages(file string, has_label bool) ([]Image, []int, error) {
+	images, labels, _, err := DefaultCSVSchema(has_label).ExtractImages(file)
+	return images, labels, err
 }
 
-// GetBlackWhite returns the image after forcing all pixels less than the
-// threshold to black and all pixels greater than or equal to the threshold
-// to white.
-func GetBlackWhite(image [][]int, threshold int) [][]int {
+// GetBlackWhite returns a binary copy of the image in which all pixels less
+// than the threshold are black and all pixels greater than or equal to the
+// threshold are white. The provided image is not modified.
+func GetBlackWhite(image Image, threshold int) Image {
 	black, white := 0, 1
 
-	for i := 0; i < len(image); i++ {
-		for j := 0; j < len(image[i]); j++ {
-			if image[i][j] < threshold {
-				image[i][j] = black
-			} else {
-				image[i][j] = white
-			}
+	binary_image := image.Blank(Binary)
+	for i, value := range image.Pixels {
+		if value < threshold {
+			binary_image.Pixels[i] = black
+		} else {
+			binary_image.Pixels[i] = white
 		}
 	}
-	return image
+	return binary_image
 }
 
 // GetRandomWeights returns a slice of random weights with the provided
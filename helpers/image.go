@@ -0,0 +1,182 @@
+package helpers
+
+import (
+	"fmt"
+	goimage "image"
+	"image/color"
+	_ "image/gif"  // register the GIF decoder for ReadImageFile
+	_ "image/jpeg" // register the JPEG decoder for ReadImageFile
+	_ "image/png"  // register the PNG decoder for ReadImageFile
+	"os"
+)
+
+// Depth names the range of values held by the pixels of an Image.
+type Depth string
+
+const (
+	Greyscale Depth = "greyscale" // from 0 (background) to 255 (ink)
+	Binary    Depth = "binary"    // 0 (background) or 1 (ink)
+)
+
+// MaxValue returns the largest value a pixel of the provided depth may hold.
+func (depth Depth) MaxValue() int {
+	if depth == Binary {
+		return 1
+	}
+	return 255
+}
+
+// Image is a rectangular image of any size. Its pixels are stored row by row
+// in Pixels, so the pixel at row r and column c is Pixels[r*Width+c].
+type Image struct {
+	Width, Height int
+	Depth         Depth
+	Pixels        []int
+}
+
+// NewImage returns a blank (all background) image of the provided size and
+// depth, or an error if either dimension is not positive.
+func NewImage(width, height int, depth Depth) (Image, error) {
+	if width < 1 || height < 1 {
+		return Image{}, fmt.Errorf("image size must be positive, got %dx%d", width, height)
+	}
+	if depth != Greyscale && depth != Binary {
+		return Image{}, fmt.Errorf("unknown pixel depth %q", depth)
+	}
+	return Image{Width: width, Height: height, Depth: depth, Pixels: make([]int, width*height)}, nil
+}
+
+// NewImageFromMatrix returns an image of the provided depth holding the
+// provided matrix, which is indexed by row and then column. The matrix must
+// be rectangular and non-empty, and its values must fit the depth.
+func NewImageFromMatrix(matrix [][]int, depth Depth) (Image, error) {
+	if len(matrix) == 0 {
+		return Image{}, fmt.Errorf("image has no rows")
+	}
+
+	image, err := NewImage(len(matrix[0]), len(matrix), depth)
+	if err != nil {
+		return Image{}, err
+	}
+
+	for row := range matrix {
+		if len(matrix[row]) != image.Width {
+			return Image{}, fmt.Errorf("row %d of image has %d columns, expected %d", row, len(matrix[row]), image.Width)
+		}
+		copy(image.Pixels[row*image.Width:], matrix[row])
+	}
+
+	if err := image.Validate(); err != nil {
+		return Image{}, err
+	}
+	return image, nil
+}
+
+// ReadImageFile decodes the provided PNG, JPEG or GIF file into a greyscale
+// image holding the brightness of each pixel. The data sets hold bright ink
+// on a dark background, so a scan of dark ink on light paper should be
+// inverted with Invert before it is classified.
+func ReadImageFile(file string) (Image, error) {
+	image_file, err := os.Open(file)
+	if err != nil {
+		return Image{}, err
+	}
+	defer image_file.Close()
+
+	decoded, _, err := goimage.Decode(image_file)
+	if err != nil {
+		return Image{}, fmt.Errorf("%s: %v", file, err)
+	}
+
+	bounds := decoded.Bounds()
+	image, err := NewImage(bounds.Dx(), bounds.Dy(), Greyscale)
+	if err != nil {
+		return Image{}, fmt.Errorf("%s: %v", file, err)
+	}
+
+	for row := 0; row < image.Height; row++ {
+		for col := 0; col < image.Width; col++ {
+			grey := color.GrayModel.Convert(decoded.At(bounds.Min.X+col, bounds.Min.Y+row)).(color.Gray)
+			image.Set(row, col, int(grey.Y))
+		}
+	}
+	return image, nil
+}
+
+// Validate returns an error unless the image's dimensions are positive, it
+// has one pixel per position and every pixel fits its depth.
+func (image Image) Validate() error {
+	if image.Width < 1 || image.Height < 1 {
+		return fmt.Errorf("image size must be positive, got %dx%d", image.Width, image.Height)
+	}
+	if len(image.Pixels) != image.Width*image.Height {
+		return fmt.Errorf("image has %d pixels, expected %dx%d", len(image.Pixels), image.Width, image.Height)
+	}
+
+	max_value := image.Depth.MaxValue()
+	for i, value := range image.Pixels {
+		if value < 0 || value > max_value {
+			return fmt.Errorf("pixel at row %d, column %d is %d, expected %s value between 0 and %d",
+				i/image.Width, i%image.Width, value, image.Depth, max_value)
+		}
+	}
+	return nil
+}
+
+// Contains reports whether the provided row and column lie inside the image.
+func (image Image) Contains(row, col int) bool {
+	return 0 <= row && row < image.Height && 0 <= col && col < image.Width
+}
+
+// At returns the pixel at the provided row and column. Positions outside the
+// image are background (0), so neighbourhood operations need no special case
+// at the edges.
+func (image Image) At(row, col int) int {
+	if !image.Contains(row, col) {
+		return 0
+	}
+	return image.Pixels[row*image.Width+col]
+}
+
+// Set sets the pixel at the provided row and column. It panics if the
+// position is outside the image.
+func (image Image) Set(row, col, value int) {
+	if !image.Contains(row, col) {
+		panic(fmt.Sprintf("pixel (%d, %d) is outside the %dx%d image", row, col, image.Width, image.Height))
+	}
+	image.Pixels[row*image.Width+col] = value
+}
+
+// Copy returns a deep copy of the image.
+func (image Image) Copy() Image {
+	copied := image
+	copied.Pixels = append([]int(nil), image.Pixels...)
+	return copied
+}
+
+// Blank returns an image of the same size as the image with every pixel set
+// to background, at the provided depth.
+func (image Image) Blank(depth Depth) Image {
+	return Image{Width: image.Width, Height: image.Height, Depth: depth, Pixels: make([]int, len(image.Pixels))}
+}
+
+// Matrix returns the pixels of the image as a matrix indexed by row and then
+// column.
+func (image Image) Matrix() [][]int {
+	matrix := make([][]int, image.Height)
+	for row := range matrix {
+		matrix[row] = append([]int(nil), image.Pixels[row*image.Width:(row+1)*image.Width]...)
+	}
+	return matrix
+}
+
+// Invert returns a copy of the image with every pixel replaced by the largest
+// value of its depth minus the pixel, swapping ink and background.
+func (image Image) Invert() Image {
+	inverted := image.Copy()
+	max_value := image.Depth.MaxValue()
+	for i, value := range inverted.Pixels {
+		inverted.Pixels[i] = max_value - value
+	}
+	return inverted
+}
@@ -0,0 +1,357 @@
+package helpers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CSVError describes a malformed row of an image CSV file.
+type CSVError struct {
+	File   string
+	Line   int // line of the file, counting from 1
+	Column int // field of the row, counting from 1, or 0 if the whole row is at fault
+	Err    error
+}
+
+func (csv_error *CSVError) Error() string {
+	if csv_error.Column == 0 {
+		return fmt.Sprintf("%s:%d: %v", csv_error.File, csv_error.Line, csv_error.Err)
+	}
+	return fmt.Sprintf("%s:%d: column %d: %v", csv_error.File, csv_error.Line, csv_error.Column, csv_error.Err)
+}
+
+func (csv_error *CSVError) Unwrap() error {
+	return csv_error.Err
+}
+
+// HeaderMode says whether the first line of an image CSV file is a header.
+type HeaderMode string
+
+const (
+	HeaderPresent HeaderMode = "present" // the first line is a header
+	HeaderAbsent  HeaderMode = "absent"  // the first line is a row of data
+	HeaderDetect  HeaderMode = "detect"  // the first line is a header if any of its fields is not a number
+)
+
+// CSVSchema describes the layout of an image CSV file: one image per row,
+// with its pixel values row by row in every column but the label column.
+type CSVSchema struct {
+	Header HeaderMode
+
+	// LabelColumn is the index of the column holding the class label,
+	// counting from 0, or -1 if the file is unlabelled. If LabelName is set,
+	// the label is instead the column of that name in the header.
+	LabelColumn int
+	LabelName   string
+
+	Width, Height int
+
+	// Pixel values must lie between MinValue (background) and MaxValue (ink),
+	// and are scaled to the greyscale range of 0 to 255.
+	MinValue, MaxValue float64
+
+	// If SkipBadRows is set, malformed rows are skipped and recorded rather
+	// than ending the read; see ImageReader.Skipped.
+	SkipBadRows bool
+}
+
+// DefaultCSVSchema returns the layout of the files in input_files: a header
+// line, then rows of 28x28 greyscale images from 0 to 255, each preceded by
+// its class label if has_label is true.
+func DefaultCSVSchema(has_label bool) CSVSchema {
+	label_column := -1
+	if has_label {
+		label_column = 0
+	}
+	return CSVSchema{
+		Header:      HeaderPresent,
+		LabelColumn: label_column,
+		Width:       28,
+		Height:      28,
+		MinValue:    0,
+		MaxValue:    255,
+	}
+}
+
+// Validate returns an error unless the schema describes a possible layout.
+func (schema CSVSchema) Validate() error {
+	switch schema.Header {
+	case HeaderPresent, HeaderAbsent, HeaderDetect:
+	default:
+		return fmt.Errorf("unknown header mode %q", schema.Header)
+	}
+	if schema.LabelName != "" && schema.Header == HeaderAbsent {
+		return fmt.Errorf("label column %q cannot be found without a header", schema.LabelName)
+	}
+	if schema.LabelColumn < -1 {
+		return fmt.Errorf("label column must be -1 or more, got %d", schema.LabelColumn)
+	}
+	if schema.Width < 1 || schema.Height < 1 {
+		return fmt.Errorf("image size must be positive, got %dx%d", schema.Width, schema.Height)
+	}
+	if !(schema.MinValue < schema.MaxValue) {
+		return fmt.Errorf("pixel value range must be increasing, got %v to %v", schema.MinValue, schema.MaxValue)
+	}
+	return nil
+}
+
+// ExtractImages reads every image of the provided file as described by the
+// schema, returning the images, their class labels (nil if the file is
+// unlabelled) and the rows that were skipped because they were malformed.
+func (schema CSVSchema) ExtractImages(file string) ([]Image, []int, []*CSVError, error) {
+	reader, err := schema.Open(file)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer reader.Close()
+
+	var images []Image
+	var labels []int
+
+	for {
+		image, label, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		images = append(images, image)
+		if reader.label_column >= 0 {
+			labels = append(labels, label)
+		}
+	}
+
+	return images, labels, reader.Skipped(), nil
+}
+
+// ImageReader reads the images of a CSV file one row at a time, so that a file
+// of any size can be processed in bounded memory.
+type ImageReader struct {
+	file     string
+	schema   CSVSchema
+	csv_file *os.File
+	reader   *csv.Reader
+
+	label_column int      // index of the label column, or -1
+	pending      []string // first row of data, if it was read looking for a header
+	pending_line int
+	row          int // index of the last row read among the rows of data, from 0
+	skipped      []*CSVError
+}
+
+// NewImageReader opens the provided CSV file of images laid out as described
+// by DefaultCSVSchema. The reader must be closed once it is no longer needed.
+func NewImageReader(file string, has_label bool) (*ImageReader, error) {
+	return DefaultCSVSchema(has_label).Open(file)
+}
+
+// Open opens the provided CSV file of images laid out as described by the
+// schema and reads its header, if it has one. The reader must be closed once
+// it is no longer needed.
+func (schema CSVSchema) Open(file string) (*ImageReader, error) {
+	if err := schema.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	csv_file, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(csv_file)
+	reader.FieldsPerRecord = -1 // rows of the wrong length are reported by Next
+	reader.ReuseRecord = true
+
+	image_reader := &ImageReader{
+		file:         file,
+		schema:       schema,
+		csv_file:     csv_file,
+		reader:       reader,
+		label_column: schema.LabelColumn,
+		row:          -1,
+	}
+	if err := image_reader.readHeader(); err != nil {
+		csv_file.Close()
+		return nil, err
+	}
+	return image_reader, nil
+}
+
+// readHeader reads the first line of the file and, if it is a header, finds
+// the label column in it. Otherwise the line is kept for Next.
+func (image_reader *ImageReader) readHeader() error {
+	schema := image_reader.schema
+	if schema.Header == HeaderAbsent {
+		return nil
+	}
+
+	first_line, err := image_reader.reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return image_reader.wrapError(err)
+	}
+	first_line[0] = strings.TrimPrefix(first_line[0], "\ufeff")
+
+	is_header := schema.Header == HeaderPresent
+	for _, field := range first_line {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(field), 64); err != nil {
+			is_header = true
+		}
+	}
+	if !is_header {
+		image_reader.pending = append([]string(nil), first_line...)
+		image_reader.pending_line, _ = image_reader.reader.FieldPos(0)
+		if schema.LabelName != "" {
+			return &CSVError{File: image_reader.file, Line: image_reader.pending_line,
+				Err: fmt.Errorf("no header in which to find label column %q", schema.LabelName)}
+		}
+		return nil
+	}
+
+	if schema.LabelName != "" {
+		image_reader.label_column = -1
+		for i, name := range first_line {
+			if strings.TrimSpace(name) == schema.LabelName {
+				image_reader.label_column = i
+				break
+			}
+		}
+		if image_reader.label_column < 0 {
+			return &CSVError{File: image_reader.file, Line: 1, Err: fmt.Errorf("header has no column %q", schema.LabelName)}
+		}
+	}
+	return nil
+}
+
+// Next returns the image in the next row of the file and its class label, or
+// -1 if the file is unlabelled. It returns io.EOF once every row has been
+// read, and a *CSVError locating the fault if the row is malformed, unless
+// the schema skips bad rows.
+func (image_reader *ImageReader) Next() (Image, int, error) {
+	for {
+		record, line, err := image_reader.readRecord()
+		if err == io.EOF {
+			return Image{}, -1, err
+		}
+		image_reader.row++
+
+		image, label := Image{}, -1
+		if err == nil {
+			image, label, err = image_reader.parseRecord(record, line)
+		}
+
+		var csv_error *CSVError
+		if err != nil && image_reader.schema.SkipBadRows && errors.As(err, &csv_error) {
+			image_reader.skipped = append(image_reader.skipped, csv_error)
+			continue
+		}
+		return image, label, err
+	}
+}
+
+// Row returns the index of the row of data last read by Next, counting from 0
+// and including any skipped rows, so that an image can be traced back to its
+// row of the file.
+func (image_reader *ImageReader) Row() int {
+	return image_reader.row
+}
+
+// Skipped returns the errors of the malformed rows skipped so far.
+func (image_reader *ImageReader) Skipped() []*CSVError {
+	return image_reader.skipped
+}
+
+// Close closes the file.
+func (image_reader *ImageReader) Close() error {
+	return image_reader.csv_file.Close()
+}
+
+// readRecord returns the next row of the file and its line.
+func (image_reader *ImageReader) readRecord() ([]string, int, error) {
+	if image_reader.pending != nil {
+		record, line := image_reader.pending, image_reader.pending_line
+		image_reader.pending = nil
+		return record, line, nil
+	}
+
+	record, err := image_reader.reader.Read()
+	if err != nil {
+		return nil, 0, image_reader.wrapError(err)
+	}
+	line, _ := image_reader.reader.FieldPos(0)
+	return record, line, nil
+}
+
+// parseRecord returns the image and class label held by the provided row,
+// which was read from the provided line.
+func (image_reader *ImageReader) parseRecord(record []string, line int) (Image, int, error) {
+	schema, label_column := image_reader.schema, image_reader.label_column
+	newError := func(column int, err error) error {
+		return &CSVError{File: image_reader.file, Line: line, Column: column, Err: err}
+	}
+
+	num_pixels := schema.Width * schema.Height
+	expected_length := num_pixels
+	if label_column >= 0 {
+		expected_length++
+	}
+	if len(record) != expected_length {
+		return Image{}, -1, newError(0, fmt.Errorf("row has %d values, expected %d", len(record), expected_length))
+	}
+	if label_column >= len(record) {
+		return Image{}, -1, newError(0, fmt.Errorf("row has no label column %d", label_column+1))
+	}
+
+	label := -1
+	if label_column >= 0 {
+		value, err := strconv.Atoi(strings.TrimSpace(record[label_column]))
+		if err != nil || value < 0 {
+			return Image{}, -1, newError(label_column+1, fmt.Errorf("label %q is not a non-negative integer", record[label_column]))
+		}
+		label = value
+	}
+
+	image, err := NewImage(schema.Width, schema.Height, Greyscale)
+	if err != nil {
+		return Image{}, -1, err
+	}
+
+	scale := float64(Greyscale.MaxValue()) / (schema.MaxValue - schema.MinValue)
+	pixel := 0
+	for i, field := range record {
+		if i == label_column {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || !(schema.MinValue <= value && value <= schema.MaxValue) {
+			return Image{}, -1, newError(i+1, fmt.Errorf("pixel value %q is not a number between %v and %v", field, schema.MinValue, schema.MaxValue))
+		}
+		image.Pixels[pixel] = int(math.Round((value - schema.MinValue) * scale))
+		pixel++
+	}
+
+	return image, label, nil
+}
+
+// wrapError returns the provided error from the underlying CSV reader as a
+// *CSVError, leaving io.EOF unchanged.
+func (image_reader *ImageReader) wrapError(err error) error {
+	var parse_error *csv.ParseError
+	if errors.As(err, &parse_error) {
+		return &CSVError{File: image_reader.file, Line: parse_error.Line, Err: parse_error.Err}
+	}
+	if err == io.EOF {
+		return err
+	}
+	return fmt.Errorf("%s: %v", image_reader.file, err)
+}
@@ -0,0 +1,96 @@
+package helpers
+
+import "fmt"
+
+// Matrix is a dense matrix of float64 values. Its elements are stored row by
+// row in Data, so the element at row i and column j is Data[i*NumCols+j].
+type Matrix struct {
+	NumRows, NumCols int
+	Data             []float64
+}
+
+// NewMatrix returns a num_rows by num_cols matrix of zeros.
+func NewMatrix(num_rows, num_cols int) Matrix {
+	return Matrix{NumRows: num_rows, NumCols: num_cols, Data: make([]float64, num_rows*num_cols)}
+}
+
+// NewMatrixFromRows returns a matrix holding a copy of the provided rows,
+// which must all have the same length.
+func NewMatrixFromRows(rows [][]float64) (Matrix, error) {
+	if len(rows) == 0 {
+		return Matrix{}, fmt.Errorf("matrix has no rows")
+	}
+
+	matrix := NewMatrix(len(rows), len(rows[0]))
+	for i, row := range rows {
+		if len(row) != matrix.NumCols {
+			return Matrix{}, fmt.Errorf("row %d of matrix has %d columns, expected %d", i, len(row), matrix.NumCols)
+		}
+		copy(matrix.Row(i), row)
+	}
+	return matrix, nil
+}
+
+// At returns the element at the provided row and column.
+func (matrix Matrix) At(i, j int) float64 {
+	return matrix.Data[i*matrix.NumCols+j]
+}
+
+// Set sets the element at the provided row and column.
+func (matrix Matrix) Set(i, j int, value float64) {
+	matrix.Data[i*matrix.NumCols+j] = value
+}
+
+// Row returns the provided row of the matrix. The returned slice shares the
+// matrix's storage, so changing one changes the other.
+func (matrix Matrix) Row(i int) []float64 {
+	return matrix.Data[i*matrix.NumCols : (i+1)*matrix.NumCols : (i+1)*matrix.NumCols]
+}
+
+// Rows returns every row of the matrix as a slice sharing the matrix's
+// storage, for use with functions that take weight vectors as [][]float64.
+func (matrix Matrix) Rows() [][]float64 {
+	rows := make([][]float64, matrix.NumRows)
+	for i := range rows {
+		rows[i] = matrix.Row(i)
+	}
+	return rows
+}
+
+// Copy returns a deep copy of the matrix.
+func (matrix Matrix) Copy() Matrix {
+	copied := matrix
+	copied.Data = append([]float64(nil), matrix.Data...)
+	return copied
+}
+
+// CopyFrom overwrites the matrix with the elements of the provided matrix,
+// which must have the same shape, without allocating.
+func (matrix Matrix) CopyFrom(source Matrix) {
+	if matrix.NumRows != source.NumRows || matrix.NumCols != source.NumCols {
+		panic("Copy between matrices of unequal shape")
+	}
+	copy(matrix.Data, source.Data)
+}
+
+// MulVec returns the product of the matrix and the provided vector, writing
+// it to dst if dst has room for NumRows elements and to a new slice
+// otherwise. Passing the same dst on every call makes MulVec allocation-free.
+func (matrix Matrix) MulVec(vector, dst []float64) []float64 {
+	if len(vector) != matrix.NumCols {
+		panic(fmt.Sprintf("product of %dx%d matrix and vector of length %d", matrix.NumRows, matrix.NumCols, len(vector)))
+	}
+	if cap(dst) < matrix.NumRows {
+		dst = make([]float64, matrix.NumRows)
+	}
+	dst = dst[:matrix.NumRows]
+
+	for i := range dst {
+		sum := 0.0
+		for j, value := range matrix.Data[i*matrix.NumCols : (i+1)*matrix.NumCols] {
+			sum += value * vector[j]
+		}
+		dst[i] = sum
+	}
+	return dst
+}
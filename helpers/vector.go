@@ -0,0 +1,54 @@
+package helpers
+
+import "fmt"
+
+// CheckedDotProduct returns the dot product of the provided vectors, or an
+// error if they differ in length.
+func CheckedDotProduct(a, b []float64) (float64, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("dot product of vectors of unequal length %d and %d", len(a), len(b))
+	}
+	return DotProduct(a, b), nil
+}
+
+// AddInPlace adds b to a element-wise, overwriting a.
+func AddInPlace(a, b []float64) {
+	if len(a) != len(b) {
+		panic("Addition of vectors of unequal length")
+	}
+
+	for i := range a {
+		a[i] += b[i]
+	}
+}
+
+// SubtractInPlace subtracts b from a element-wise, overwriting a.
+func SubtractInPlace(a, b []float64) {
+	if len(a) != len(b) {
+		panic("Subtraction of vectors of unequal length")
+	}
+
+	for i := range a {
+		a[i] -= b[i]
+	}
+}
+
+// ScaleInPlace multiplies each element of the provided vector by the provided
+// scalar, overwriting the vector.
+func ScaleInPlace(vector []float64, scalar float64) {
+	for i := range vector {
+		vector[i] *= scalar
+	}
+}
+
+// Axpy adds alpha times x to y element-wise, overwriting y. It replaces the
+// AddVectors(y, Multiply(x, alpha)) pattern without allocating.
+func Axpy(alpha float64, x, y []float64) {
+	if len(x) != len(y) {
+		panic("Axpy of vectors of unequal length")
+	}
+
+	for i := range x {
+		y[i] += alpha * x[i]
+	}
+}
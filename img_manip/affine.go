@@ -0,0 +1,185 @@
+// Package img_manip implements functions for manipulating images.
+package img_manip
+
+import (
+	"fmt"
+	"math"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// Interpolation names the way a transformed image is sampled between pixels.
+type Interpolation string
+
+const (
+	NearestNeighbour Interpolation = "nearest"
+	Bilinear         Interpolation = "bilinear"
+)
+
+// Affine is an affine transform of the plane. It maps the point at column x
+// and row y to column A[0][0]*x + A[0][1]*y + A[0][2] and row
+// A[1][0]*x + A[1][1]*y + A[1][2].
+type Affine [2][3]float64
+
+// Identity returns the transform that leaves every point in place.
+func Identity() Affine {
+	return Affine{{1, 0, 0}, {0, 1, 0}}
+}
+
+// Translation returns the transform that moves every point down by rows and
+// right by cols.
+func Translation(rows, cols float64) Affine {
+	return Affine{{1, 0, cols}, {0, 1, rows}}
+}
+
+// Rotation returns the transform that rotates every point clockwise by the
+// provided angle, in degrees, about the provided centre.
+func Rotation(degrees, center_row, center_col float64) Affine {
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	rotation := Affine{{cos, -sin, 0}, {sin, cos, 0}}
+	return aboutCenter(rotation, center_row, center_col)
+}
+
+// Scaling returns the transform that scales every point away from the
+// provided centre by row_scale vertically and col_scale horizontally.
+func Scaling(row_scale, col_scale, center_row, center_col float64) Affine {
+	scaling := Affine{{col_scale, 0, 0}, {0, row_scale, 0}}
+	return aboutCenter(scaling, center_row, center_col)
+}
+
+// Shear returns the transform that shifts every point horizontally by shear
+// times its distance below the provided centre row, slanting vertical strokes
+// to the left for positive shear.
+func Shear(shear, center_row, center_col float64) Affine {
+	shearing := Affine{{1, -shear, 0}, {0, 1, 0}}
+	return aboutCenter(shearing, center_row, center_col)
+}
+
+// Then returns the transform that applies a and then b.
+func (a Affine) Then(b Affine) Affine {
+	var composed Affine
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 3; j++ {
+			composed[i][j] = b[i][0]*a[0][j] + b[i][1]*a[1][j]
+		}
+		composed[i][2] += b[i][2]
+	}
+	return composed
+}
+
+// Invert returns the transform that undoes a, or an error if a collapses the
+// plane onto a line or a point.
+func (a Affine) Invert() (Affine, error) {
+	determinant := a[0][0]*a[1][1] - a[0][1]*a[1][0]
+	if math.Abs(determinant) < 1e-12 {
+		return Affine{}, fmt.Errorf("affine transform %v is not invertible", a)
+	}
+
+	inverse := Affine{
+		{a[1][1] / determinant, -a[0][1] / determinant, 0},
+		{-a[1][0] / determinant, a[0][0] / determinant, 0},
+	}
+	inverse[0][2] = -(inverse[0][0]*a[0][2] + inverse[0][1]*a[1][2])
+	inverse[1][2] = -(inverse[1][0]*a[0][2] + inverse[1][1]*a[1][2])
+	return inverse, nil
+}
+
+// Apply returns the column and row that the provided point is mapped to.
+func (a Affine) Apply(col, row float64) (float64, float64) {
+	return a[0][0]*col + a[0][1]*row + a[0][2], a[1][0]*col + a[1][1]*row + a[1][2]
+}
+
+// Transform returns a num_rows by num_cols image in which the pixel at each
+// point is sampled from the provided image at the point that the transform
+// maps to it. Points that fall outside the provided image are background (0).
+// The provided image may be of any size and is not modified; the result has
+// the same depth.
+func Transform(image helpers.Image, transform Affine, num_rows, num_cols int, interpolation Interpolation) (helpers.Image, error) {
+	inverse, err := transform.Invert()
+	if err != nil {
+		return helpers.Image{}, err
+	}
+
+	sample := getSampler(image, interpolation)
+	if sample == nil {
+		return helpers.Image{}, fmt.Errorf("unknown interpolation %q", interpolation)
+	}
+
+	transformed, err := helpers.NewImage(num_cols, num_rows, image.Depth)
+	if err != nil {
+		return helpers.Image{}, err
+	}
+	for row := 0; row < num_rows; row++ {
+		for col := 0; col < num_cols; col++ {
+			source_col, source_row := inverse.Apply(float64(col), float64(row))
+			transformed.Set(row, col, sample(source_row, source_col))
+		}
+	}
+	return transformed, nil
+}
+
+// Rotate returns the provided image rotated clockwise by the provided angle,
+// in degrees, about its centre, keeping its size.
+func Rotate(image helpers.Image, degrees float64, interpolation Interpolation) (helpers.Image, error) {
+	center_row, center_col := getCenter(image)
+	return Transform(image, Rotation(degrees, center_row, center_col), image.Height, image.Width, interpolation)
+}
+
+// Scale returns the provided image scaled about its centre by row_scale
+// vertically and col_scale horizontally, keeping its size.
+func Scale(image helpers.Image, row_scale, col_scale float64, interpolation Interpolation) (helpers.Image, error) {
+	center_row, center_col := getCenter(image)
+	return Transform(image, Scaling(row_scale, col_scale, center_row, center_col), image.Height, image.Width, interpolation)
+}
+
+// Translate returns the provided image moved down by rows and right by cols,
+// keeping its size.
+func Translate(image helpers.Image, rows, cols float64, interpolation Interpolation) (helpers.Image, error) {
+	return Transform(image, Translation(rows, cols), image.Height, image.Width, interpolation)
+}
+
+// ShearImage returns the provided image sheared horizontally about its centre
+// by the provided factor (see Shear), keeping its size.
+func ShearImage(image helpers.Image, shear float64, interpolation Interpolation) (helpers.Image, error) {
+	center_row, center_col := getCenter(image)
+	return Transform(image, Shear(shear, center_row, center_col), image.Height, image.Width, interpolation)
+}
+
+// aboutCenter returns the provided linear transform applied about the provided
+// centre rather than about the origin.
+func aboutCenter(linear Affine, center_row, center_col float64) Affine {
+	return Translation(-center_row, -center_col).Then(linear).Then(Translation(center_row, center_col))
+}
+
+// getCenter returns the row and column of the centre of the provided image.
+func getCenter(image helpers.Image) (float64, float64) {
+	return float64(image.Height-1) / 2, float64(image.Width-1) / 2
+}
+
+// getSampler returns a function that samples the provided image at a
+// fractional row and column with the provided interpolation, or nil if the
+// interpolation is unknown. Points outside the image are background (0).
+func getSampler(image helpers.Image, interpolation Interpolation) func(row, col float64) int {
+	pixel := func(row, col int) float64 {
+		return float64(image.At(row, col))
+	}
+
+	switch interpolation {
+	case NearestNeighbour:
+		return func(row, col float64) int {
+			return int(pixel(int(math.Round(row)), int(math.Round(col))))
+		}
+	case Bilinear:
+		return func(row, col float64) int {
+			top, left := math.Floor(row), math.Floor(col)
+			dy, dx := row-top, col-left
+			r, c := int(top), int(left)
+
+			value := pixel(r, c)*(1-dy)*(1-dx) + pixel(r, c+1)*(1-dy)*dx +
+				pixel(r+1, c)*dy*(1-dx) + pixel(r+1, c+1)*dy*dx
+			return int(math.Round(value))
+		}
+	default:
+		return nil
+	}
+}
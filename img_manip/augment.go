@@ -0,0 +1,163 @@
+// Package img_manip implements functions for manipulating images.
+package img_manip
+
+import (
+	"math"
+	"math/rand"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// Augmentation describes the random distortions applied by Augment to make
+// new training images from existing ones. Each distortion is disabled by
+// leaving its field zero.
+type Augmentation struct {
+	Multiplier int   // number of distorted copies made of each image
+	Seed       int64 // seeds the random distortions
+
+	MaxRotation float64 // largest rotation, in degrees, either way
+	MaxShift    float64 // largest shift, in pixels, along each axis
+	MaxScale    float64 // largest change in size, as a fraction, either way
+
+	ElasticAlpha float64 // strength of the elastic distortion, in pixels
+	ElasticSigma float64 // smoothness of the elastic distortion, in pixels
+
+	StrokeProbability float64 // chance of thickening or thinning the strokes
+	NoiseProbability  float64 // chance of each pixel being replaced by noise
+}
+
+// DefaultAugmentation returns small distortions that keep digits legible:
+// rotations of up to 10 degrees, shifts of up to 2 pixels, 10% scaling, a mild
+// elastic distortion, occasional stroke thickening or thinning and 1% noise.
+func DefaultAugmentation(multiplier int, seed int64) Augmentation {
+	return Augmentation{
+		Multiplier:        multiplier,
+		Seed:              seed,
+		MaxRotation:       10,
+		MaxShift:          2,
+		MaxScale:          0.1,
+		ElasticAlpha:      34,
+		ElasticSigma:      4,
+		StrokeProbability: 0.3,
+		NoiseProbability:  0.01,
+	}
+}
+
+// Augment returns a randomly distorted copy of the provided greyscale image,
+// drawing every random choice from the provided source. The provided image is
+// not modified.
+func Augment(image helpers.Image, augmentation Augmentation, random *rand.Rand) (helpers.Image, error) {
+	uniform := func(limit float64) float64 {
+		return (2*random.Float64() - 1) * limit
+	}
+
+	center_row, center_col := getCenter(image)
+	scale := 1 + uniform(augmentation.MaxScale)
+	transform := Rotation(uniform(augmentation.MaxRotation), center_row, center_col).
+		Then(Scaling(scale, scale, center_row, center_col)).
+		Then(Translation(uniform(augmentation.MaxShift), uniform(augmentation.MaxShift)))
+
+	augmented, err := Transform(image, transform, image.Height, image.Width, Bilinear)
+	if err != nil {
+		return helpers.Image{}, err
+	}
+
+	if augmentation.ElasticAlpha > 0 {
+		augmented = ElasticDistort(augmented, augmentation.ElasticAlpha, augmentation.ElasticSigma, random)
+	}
+
+	if random.Float64() < augmentation.StrokeProbability {
+		element, err := NewStructuringElement(Cross, 3)
+		if err != nil {
+			return helpers.Image{}, err
+		}
+		if random.Intn(2) == 0 {
+			augmented = Dilate(augmented, element)
+		} else {
+			augmented = Erode(augmented, element)
+		}
+	}
+
+	if augmentation.NoiseProbability > 0 {
+		for i := range augmented.Pixels {
+			if random.Float64() < augmentation.NoiseProbability {
+				augmented.Pixels[i] = random.Intn(augmented.Depth.MaxValue() + 1)
+			}
+		}
+	}
+
+	return augmented, nil
+}
+
+// ElasticDistort returns the provided image with each pixel displaced by a
+// random, smoothly varying amount, as described by Simard et al. (2003). The
+// displacement field is drawn uniformly from [-1, 1], smoothed with a Gaussian
+// of standard deviation sigma and scaled by alpha.
+func ElasticDistort(image helpers.Image, alpha, sigma float64, random *rand.Rand) helpers.Image {
+	num_rows, num_cols := image.Height, image.Width
+
+	fields := [2][][]float64{}
+	for f := range fields {
+		field := make([][]float64, num_rows)
+		for row := range field {
+			field[row] = make([]float64, num_cols)
+			for col := range field[row] {
+				field[row][col] = 2*random.Float64() - 1
+			}
+		}
+		fields[f] = gaussianBlur(field, sigma)
+	}
+
+	sample := getSampler(image, Bilinear)
+	distorted := image.Blank(image.Depth)
+	for row := 0; row < num_rows; row++ {
+		for col := 0; col < num_cols; col++ {
+			source_row := float64(row) + alpha*fields[0][row][col]
+			source_col := float64(col) + alpha*fields[1][row][col]
+			distorted.Set(row, col, sample(source_row, source_col))
+		}
+	}
+	return distorted
+}
+
+// gaussianBlur returns the provided field convolved with a Gaussian of
+// standard deviation sigma, treating values beyond the edges as zero.
+func gaussianBlur(field [][]float64, sigma float64) [][]float64 {
+	if sigma <= 0 {
+		return field
+	}
+
+	radius := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*radius+1)
+	total := 0.0
+	for i := range kernel {
+		x := float64(i - radius)
+		kernel[i] = math.Exp(-x * x / (2 * sigma * sigma))
+		total += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= total
+	}
+
+	num_rows, num_cols := len(field), len(field[0])
+	blur := func(source [][]float64, along_rows bool) [][]float64 {
+		blurred := make([][]float64, num_rows)
+		for row := range blurred {
+			blurred[row] = make([]float64, num_cols)
+			for col := range blurred[row] {
+				for i, weight := range kernel {
+					r, c := row, col+i-radius
+					if along_rows {
+						r, c = row+i-radius, col
+					}
+					if 0 <= r && r < num_rows && 0 <= c && c < num_cols {
+						blurred[row][col] += weight * source[r][c]
+					}
+				}
+			}
+		}
+		return blurred
+	}
+
+	return blur(blur(field, false), true)
+}
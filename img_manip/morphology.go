@@ -0,0 +1,171 @@
+// Package img_manip implements functions for manipulating images.
+package img_manip
+
+import (
+	"fmt"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// Shape names the shape of a structuring element.
+type Shape string
+
+const (
+	Square Shape = "square"
+	Cross  Shape = "cross"
+	Disk   Shape = "disk"
+)
+
+// StructuringElement is the neighbourhood examined around each pixel by a
+// morphological operation. Mask marks the pixels of the neighbourhood, and
+// (OriginRow, OriginCol) is the position in Mask of the pixel being examined.
+type StructuringElement struct {
+	Mask                 [][]int
+	OriginRow, OriginCol int
+}
+
+// NewStructuringElement returns a structuring element of the provided shape
+// that fits in a size by size square, with its origin at the centre. size
+// must be odd and positive.
+func NewStructuringElement(shape Shape, size int) (StructuringElement, error) {
+	if size < 1 || size%2 == 0 {
+		return StructuringElement{}, fmt.Errorf("structuring element size must be odd and positive, got %d", size)
+	}
+
+	radius := size / 2
+	mask := make([][]int, size)
+	for row := range mask {
+		mask[row] = make([]int, size)
+		for col := range mask[row] {
+			dy, dx := row-radius, col-radius
+			switch shape {
+			case Square:
+				mask[row][col] = 1
+			case Cross:
+				if dy == 0 || dx == 0 {
+					mask[row][col] = 1
+				}
+			case Disk:
+				if dy*dy+dx*dx <= radius*radius {
+					mask[row][col] = 1
+				}
+			default:
+				return StructuringElement{}, fmt.Errorf("unknown structuring element shape %q", shape)
+			}
+		}
+	}
+
+	return StructuringElement{Mask: mask, OriginRow: radius, OriginCol: radius}, nil
+}
+
+// Erode returns the erosion of the provided image by the provided structuring
+// element: each pixel becomes the minimum of the pixels under the element. On
+// a binary image this removes ink that the element does not fit inside, such
+// as speckle noise and thin spurs; on a greyscale image it darkens. Pixels
+// outside the image are ignored.
+func Erode(image helpers.Image, element StructuringElement) helpers.Image {
+	return applyElement(image, element, func(a, b int) bool { return a < b })
+}
+
+// Dilate returns the dilation of the provided image by the provided
+// structuring element: each pixel becomes the maximum of the pixels under the
+// reflected element. On a binary image this thickens ink and bridges small
+// gaps; on a greyscale image it brightens. Pixels outside the image are
+// ignored.
+func Dilate(image helpers.Image, element StructuringElement) helpers.Image {
+	reflected := StructuringElement{
+		Mask:      make([][]int, len(element.Mask)),
+		OriginRow: len(element.Mask) - 1 - element.OriginRow,
+		OriginCol: len(element.Mask[0]) - 1 - element.OriginCol,
+	}
+	for row := range element.Mask {
+		reflected.Mask[len(element.Mask)-1-row] = make([]int, len(element.Mask[row]))
+		for col := range element.Mask[row] {
+			reflected.Mask[len(element.Mask)-1-row][len(element.Mask[row])-1-col] = element.Mask[row][col]
+		}
+	}
+
+	return applyElement(image, reflected, func(a, b int) bool { return a > b })
+}
+
+// Open returns the opening of the provided image by the provided structuring
+// element, an erosion followed by a dilation. It removes specks of ink smaller
+// than the element while leaving larger strokes mostly unchanged.
+func Open(image helpers.Image, element StructuringElement) helpers.Image {
+	return Dilate(Erode(image, element), element)
+}
+
+// Close returns the closing of the provided image by the provided structuring
+// element, a dilation followed by an erosion. It fills gaps and breaks in
+// strokes smaller than the element while leaving the strokes mostly unchanged.
+func Close(image helpers.Image, element StructuringElement) helpers.Image {
+	return Erode(Dilate(image, element), element)
+}
+
+// MorphologyOperation names a morphological operation.
+type MorphologyOperation string
+
+const (
+	ErodeOperation  MorphologyOperation = "erode"  // see Erode
+	DilateOperation MorphologyOperation = "dilate" // see Dilate
+	OpenOperation   MorphologyOperation = "open"   // see Open
+	CloseOperation  MorphologyOperation = "close"  // see Close
+)
+
+// MorphologyStep is one morphological operation applied with a structuring
+// element of the provided shape and size.
+type MorphologyStep struct {
+	Operation MorphologyOperation
+	Shape     Shape
+	Size      int
+}
+
+// ApplyMorphology returns the provided image after applying each of the
+// provided steps in order.
+func ApplyMorphology(image helpers.Image, steps []MorphologyStep) (helpers.Image, error) {
+	for _, step := range steps {
+		element, err := NewStructuringElement(step.Shape, step.Size)
+		if err != nil {
+			return helpers.Image{}, err
+		}
+
+		switch step.Operation {
+		case ErodeOperation:
+			image = Erode(image, element)
+		case DilateOperation:
+			image = Dilate(image, element)
+		case OpenOperation:
+			image = Open(image, element)
+		case CloseOperation:
+			image = Close(image, element)
+		default:
+			return helpers.Image{}, fmt.Errorf("unknown morphological operation %q", step.Operation)
+		}
+	}
+	return image, nil
+}
+
+// applyElement returns the provided image with each pixel replaced by the
+// value under the structuring element that comes first according to before.
+func applyElement(image helpers.Image, element StructuringElement, before func(a, b int) bool) helpers.Image {
+	result := image.Blank(image.Depth)
+	for row := 0; row < image.Height; row++ {
+		for col := 0; col < image.Width; col++ {
+			value := image.At(row, col)
+			for mask_row := range element.Mask {
+				for mask_col := range element.Mask[mask_row] {
+					if element.Mask[mask_row][mask_col] == 0 {
+						continue
+					}
+					r, c := row+mask_row-element.OriginRow, col+mask_col-element.OriginCol
+					if image.Contains(r, c) && before(image.At(r, c), value) {
+						value = image.At(r, c)
+					}
+				}
+			}
+			result.Set(row, col, value)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,185 @@
+// Package img_manip implements functions for manipulating images.
+package img_manip
+
+import (
+	"math"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// Normalization describes how Normalize brings a digit to a standard slant,
+// size and position, so that features computed around the centre of the image
+// (such as its symmetries) are not thrown off by where and how it was written.
+type Normalization struct {
+	Deskew        bool // shear the digit upright using its second moments
+	BoxSize       int  // rescale the digit to fit a BoxSize square, or 0 to keep its size
+	Center        bool // move the digit's centre of mass to the centre of the image
+	Interpolation Interpolation
+}
+
+// Normalize returns the provided greyscale image after deskewing it, cropping
+// it to its bounding box, rescaling it to fit the box while preserving its
+// aspect ratio, and centring it by its centre of mass, as enabled by the
+// provided normalization. The result has the same size as the provided image,
+// which is not modified. An image with no ink is returned unchanged.
+func Normalize(image helpers.Image, normalization Normalization) (helpers.Image, error) {
+	normalized := image.Copy()
+
+	if _, _, _, _, ok := GetBoundingBox(normalized); !ok {
+		return normalized, nil
+	}
+
+	var err error
+	if normalization.Deskew {
+		normalized, err = Deskew(normalized, normalization.Interpolation)
+		if err != nil {
+			return helpers.Image{}, err
+		}
+	}
+
+	if normalization.BoxSize > 0 {
+		if top, left, bottom, right, ok := GetBoundingBox(normalized); ok {
+			normalized, err = FitToBox(Crop(normalized, top, left, bottom, right),
+				normalization.BoxSize, image.Height, image.Width, normalization.Interpolation)
+			if err != nil {
+				return helpers.Image{}, err
+			}
+		}
+	}
+
+	if normalization.Center {
+		normalized, err = CenterByMass(normalized, normalization.Interpolation)
+		if err != nil {
+			return helpers.Image{}, err
+		}
+	}
+
+	return normalized, nil
+}
+
+// GetBoundingBox returns the first and last row and column of the provided
+// image that contain ink (non-zero pixels). ok is false if there is no ink.
+func GetBoundingBox(image helpers.Image) (top, left, bottom, right int, ok bool) {
+	top, left, bottom, right = image.Height, image.Width, -1, -1
+	for row := 0; row < image.Height; row++ {
+		for col := 0; col < image.Width; col++ {
+			if image.At(row, col) != 0 {
+				top, bottom = min(top, row), max(bottom, row)
+				left, right = min(left, col), max(right, col)
+			}
+		}
+	}
+	return top, left, bottom, right, bottom >= 0
+}
+
+// Crop returns the rows top to bottom and columns left to right, inclusive,
+// of the provided image. Pixels of the box that lie outside the provided
+// image are background.
+func Crop(image helpers.Image, top, left, bottom, right int) helpers.Image {
+	cropped := helpers.Image{
+		Width:  right - left + 1,
+		Height: bottom - top + 1,
+		Depth:  image.Depth,
+		Pixels: make([]int, (right-left+1)*(bottom-top+1)),
+	}
+	for row := 0; row < cropped.Height; row++ {
+		for col := 0; col < cropped.Width; col++ {
+			cropped.Set(row, col, image.At(top+row, left+col))
+		}
+	}
+	return cropped
+}
+
+// FitToBox returns a num_rows by num_cols image holding the provided image
+// scaled, preserving its aspect ratio, so that its longer side spans box_size
+// pixels, and placed in the middle. Nothing is drawn outside the area covered
+// by the scaled image, even when interpolation would spread its edges.
+func FitToBox(image helpers.Image, box_size, num_rows, num_cols int, interpolation Interpolation) (helpers.Image, error) {
+	height, width := float64(image.Height), float64(image.Width)
+	scale := float64(box_size) / math.Max(height, width)
+
+	// Scale about the centre of the source, then move that centre to the
+	// centre of the output.
+	source_row, source_col := getCenter(image)
+	target_row, target_col := float64(num_rows-1)/2, float64(num_cols-1)/2
+	transform := Scaling(scale, scale, source_row, source_col).
+		Then(Translation(target_row-source_row, target_col-source_col))
+
+	fitted, err := Transform(image, transform, num_rows, num_cols, interpolation)
+	if err != nil {
+		return helpers.Image{}, err
+	}
+
+	// Interpolation blends the edge pixels with the background for up to a
+	// source pixel beyond the image, which spans many output pixels when
+	// upscaling, so clear every pixel whose source lies outside the image.
+	inverse, err := transform.Invert()
+	if err != nil {
+		return helpers.Image{}, err
+	}
+	for row := 0; row < num_rows; row++ {
+		for col := 0; col < num_cols; col++ {
+			source_col, source_row := inverse.Apply(float64(col), float64(row))
+			if source_row < -0.5 || source_row > height-0.5 || source_col < -0.5 || source_col > width-0.5 {
+				fitted.Set(row, col, 0)
+			}
+		}
+	}
+	return fitted, nil
+}
+
+// CenterByMass returns the provided image translated so that its centre of
+// mass, weighting each pixel by its value, lies at the centre of the image.
+func CenterByMass(image helpers.Image, interpolation Interpolation) (helpers.Image, error) {
+	mass_row, mass_col, ok := getCenterOfMass(image)
+	if !ok {
+		return image.Copy(), nil
+	}
+
+	center_row, center_col := getCenter(image)
+	return Translate(image, center_row-mass_row, center_col-mass_col, interpolation)
+}
+
+// Deskew returns the provided image sheared horizontally about its centre of
+// mass so that its principal axis is vertical. The slant is estimated from
+// the image's second central moments as mu11 / mu02.
+func Deskew(image helpers.Image, interpolation Interpolation) (helpers.Image, error) {
+	mass_row, mass_col, ok := getCenterOfMass(image)
+	if !ok {
+		return image.Copy(), nil
+	}
+
+	mu11, mu02 := 0.0, 0.0
+	for row := 0; row < image.Height; row++ {
+		for col := 0; col < image.Width; col++ {
+			value := float64(image.At(row, col))
+			mu11 += value * (float64(col) - mass_col) * (float64(row) - mass_row)
+			mu02 += value * (float64(row) - mass_row) * (float64(row) - mass_row)
+		}
+	}
+	if mu02 == 0 {
+		return image.Copy(), nil
+	}
+
+	transform := Shear(mu11/mu02, mass_row, mass_col)
+	return Transform(image, transform, image.Height, image.Width, interpolation)
+}
+
+// getCenterOfMass returns the row and column of the centre of mass of the
+// provided image, weighting each pixel by its value. ok is false if the image
+// has no ink.
+func getCenterOfMass(image helpers.Image) (float64, float64, bool) {
+	mass, row_sum, col_sum := 0.0, 0.0, 0.0
+	for row := 0; row < image.Height; row++ {
+		for col := 0; col < image.Width; col++ {
+			value := float64(image.At(row, col))
+			mass += value
+			row_sum += value * float64(row)
+			col_sum += value * float64(col)
+		}
+	}
+	if mass == 0 {
+		return 0, 0, false
+	}
+	return row_sum / mass, col_sum / mass, true
+}
@@ -1,34 +1,37 @@
 // Package img_manip implements functions for manipulating images.
 package img_manip
 
-// Rotate90 rotates the provided matrix 90 degrees clockwise the provided
-// number of times.
-func Rotate90(matrix [][]int, num_rotations int) [][]int {
-	num_rotations = num_rotations % 4
+import "project04_perceptron/go_rewrite/helpers"
+
+// Rotate90 returns a copy of the provided image rotated 90 degrees clockwise
+// the provided number of times. A negative number of rotations turns the
+// image anticlockwise.
+func Rotate90(image helpers.Image, num_rotations int) helpers.Image {
+	num_rotations = (num_rotations%4 + 4) % 4
 
 	if num_rotations == 0 {
-		return matrix
+		return image.Copy()
 	}
 
-	num_rows, num_cols := len(matrix), len(matrix[0])
+	num_rows, num_cols := image.Height, image.Width
 
-	rotated_matrix := make([][]int, num_cols)
-	for col := range rotated_matrix {
-		rotated_matrix[col] = make([]int, num_rows)
+	rotated := image.Blank(image.Depth)
+	if num_rotations != 2 {
+		rotated.Width, rotated.Height = num_rows, num_cols
 	}
 
 	for row := 0; row < num_rows; row++ {
 		for col := 0; col < num_cols; col++ {
 			switch num_rotations {
 			case 1:
-				rotated_matrix[col][num_rows-row-1] = matrix[row][col]
+				rotated.Set(col, num_rows-row-1, image.At(row, col))
 			case 2:
-				rotated_matrix[num_cols-col-1][num_rows-row-1] = matrix[row][col]
+				rotated.Set(num_rows-row-1, num_cols-col-1, image.At(row, col))
 			case 3:
-				rotated_matrix[num_cols-col-1][row] = matrix[row][col]
+				rotated.Set(num_cols-col-1, row, image.At(row, col))
 			}
 		}
 	}
 
-	return rotated_matrix
+	return rotated
 }
@@ -0,0 +1,220 @@
+// Package img_manip implements functions for manipulating images.
+package img_manip
+
+import (
+	"fmt"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// ThinningMethod names an algorithm for thinning a binary image to its
+// skeleton.
+type ThinningMethod string
+
+const (
+	ZhangSuen ThinningMethod = "zhang-suen"
+	GuoHall   ThinningMethod = "guo-hall"
+)
+
+// Thin returns the skeleton of the provided binary image computed with the
+// provided thinning method.
+func Thin(image helpers.Image, method ThinningMethod) (helpers.Image, error) {
+	switch method {
+	case ZhangSuen:
+		return ZhangSuenThin(image), nil
+	case GuoHall:
+		return GuoHallThin(image), nil
+	default:
+		return helpers.Image{}, fmt.Errorf("unknown thinning method %q", method)
+	}
+}
+
+// ZhangSuenThin returns the skeleton of the provided binary image, computed
+// with the Zhang-Suen thinning algorithm. Foreground (1) pixels are peeled
+// away from the boundary in alternating sub-iterations until only a
+// one-pixel-wide, 8-connected skeleton remains. Pixels outside the image are
+// treated as background. The provided image is not modified.
+func ZhangSuenThin(image helpers.Image) helpers.Image {
+	skeleton := image.Copy()
+
+	for changed := true; changed; {
+		changed = false
+		for step := 0; step < 2; step++ {
+			to_remove := [][2]int{}
+
+			for row := 0; row < skeleton.Height; row++ {
+				for col := 0; col < skeleton.Width; col++ {
+					if skeleton.At(row, col) == 0 {
+						continue
+					}
+
+					p := getNeighbours(skeleton, row, col)
+					num_neighbours := 0
+					for _, value := range p {
+						num_neighbours += value
+					}
+					if num_neighbours < 2 || num_neighbours > 6 || getTransitions(p) != 1 {
+						continue
+					}
+
+					// p[0], p[2], p[4], p[6] are north, east, south and west.
+					if step == 0 && (p[0]*p[2]*p[4] != 0 || p[2]*p[4]*p[6] != 0) {
+						continue
+					}
+					if step == 1 && (p[0]*p[2]*p[6] != 0 || p[0]*p[4]*p[6] != 0) {
+						continue
+					}
+
+					to_remove = append(to_remove, [2]int{row, col})
+				}
+			}
+
+			for _, pixel := range to_remove {
+				skeleton.Set(pixel[0], pixel[1], 0)
+			}
+			if len(to_remove) > 0 {
+				changed = true
+			}
+		}
+	}
+
+	return skeleton
+}
+
+// GuoHallThin returns the skeleton of the provided binary image, computed with
+// the Guo-Hall thinning algorithm. It peels the boundary like ZhangSuenThin
+// but with conditions that better preserve diagonal strokes and leave fewer
+// spurious branches. Pixels outside the image are treated as background. The
+// provided image is not modified.
+func GuoHallThin(image helpers.Image) helpers.Image {
+	skeleton := image.Copy()
+
+	for changed := true; changed; {
+		changed = false
+		for step := 0; step < 2; step++ {
+			to_remove := [][2]int{}
+
+			for row := 0; row < skeleton.Height; row++ {
+				for col := 0; col < skeleton.Width; col++ {
+					if skeleton.At(row, col) == 0 {
+						continue
+					}
+
+					// p[0] to p[7] are N, NE, E, SE, S, SW, W and NW.
+					p := getNeighbours(skeleton, row, col)
+
+					c := bitNot(p[0])&bitOr(p[1], p[2]) + bitNot(p[2])&bitOr(p[3], p[4]) +
+						bitNot(p[4])&bitOr(p[5], p[6]) + bitNot(p[6])&bitOr(p[7], p[0])
+					n1 := bitOr(p[7], p[0]) + bitOr(p[1], p[2]) + bitOr(p[3], p[4]) + bitOr(p[5], p[6])
+					n2 := bitOr(p[0], p[1]) + bitOr(p[2], p[3]) + bitOr(p[4], p[5]) + bitOr(p[6], p[7])
+					n := min(n1, n2)
+
+					m := (p[4] | p[5] | bitNot(p[7])) & p[6]
+					if step == 1 {
+						m = (p[0] | p[1] | bitNot(p[3])) & p[2]
+					}
+
+					if c == 1 && 2 <= n && n <= 3 && m == 0 {
+						to_remove = append(to_remove, [2]int{row, col})
+					}
+				}
+			}
+
+			for _, pixel := range to_remove {
+				skeleton.Set(pixel[0], pixel[1], 0)
+			}
+			if len(to_remove) > 0 {
+				changed = true
+			}
+		}
+	}
+
+	return skeleton
+}
+
+// GetSkeletonLength returns the length of the provided skeleton, measured as
+// its number of foreground pixels.
+func GetSkeletonLength(skeleton helpers.Image) int {
+	length := 0
+	for _, value := range skeleton.Pixels {
+		if value != 0 {
+			length++
+		}
+	}
+	return length
+}
+
+// GetEndPoints returns the (row, column) of each pixel of the provided
+// skeleton that has exactly one foreground neighbour.
+func GetEndPoints(skeleton helpers.Image) [][2]int {
+	end_points := [][2]int{}
+	for row := 0; row < skeleton.Height; row++ {
+		for col := 0; col < skeleton.Width; col++ {
+			if skeleton.At(row, col) == 0 {
+				continue
+			}
+
+			num_neighbours := 0
+			for _, value := range getNeighbours(skeleton, row, col) {
+				num_neighbours += value
+			}
+			if num_neighbours == 1 {
+				end_points = append(end_points, [2]int{row, col})
+			}
+		}
+	}
+	return end_points
+}
+
+// GetBranchPoints returns the (row, column) of each pixel of the provided
+// skeleton where three or more branches meet, that is, where reading its
+// neighbours clockwise finds at least three separate runs of foreground.
+// Several touching pixels may be reported for a single junction.
+func GetBranchPoints(skeleton helpers.Image) [][2]int {
+	branch_points := [][2]int{}
+	for row := 0; row < skeleton.Height; row++ {
+		for col := 0; col < skeleton.Width; col++ {
+			if skeleton.At(row, col) != 0 && getTransitions(getNeighbours(skeleton, row, col)) >= 3 {
+				branch_points = append(branch_points, [2]int{row, col})
+			}
+		}
+	}
+	return branch_points
+}
+
+// getNeighbours returns the values of the eight neighbours of the provided
+// pixel, clockwise from north. Neighbours outside the image are 0.
+func getNeighbours(image helpers.Image, row, col int) [8]int {
+	offsets := [8][2]int{{-1, 0}, {-1, 1}, {0, 1}, {1, 1}, {1, 0}, {1, -1}, {0, -1}, {-1, -1}}
+
+	neighbours := [8]int{}
+	for i, offset := range offsets {
+		if image.At(row+offset[0], col+offset[1]) != 0 {
+			neighbours[i] = 1
+		}
+	}
+	return neighbours
+}
+
+// getTransitions returns the number of 0 to 1 transitions in the provided
+// neighbours, read clockwise from north and back to north.
+func getTransitions(neighbours [8]int) int {
+	transitions := 0
+	for i := range neighbours {
+		if neighbours[i] == 0 && neighbours[(i+1)%8] == 1 {
+			transitions++
+		}
+	}
+	return transitions
+}
+
+// bitOr returns 1 if either of the provided neighbour values is 1, and 0
+// otherwise.
+func bitOr(a, b int) int {
+	return a | b
+}
+
+// bitNot returns 1 if the provided neighbour value is 0, and 0 otherwise.
+func bitNot(a int) int {
+	return 1 - a
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"project04_perceptron/go_rewrite/display"
+	"project04_perceptron/go_rewrite/feature_extraction"
 	"project04_perceptron/go_rewrite/helpers"
 	"project04_perceptron/go_rewrite/model"
 )
@@ -19,10 +20,87 @@ func main() {
 	}
 	display.Stats(weights, num_successes, num_errors, epochs)
 
+	lambda := 0.0001
+	svm_weights, svm_successes, svm_errors, err := model.TrainSVM(10, 10, epochs, lambda)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	display.Stats(svm_weights, svm_successes, svm_errors, epochs)
+
+	training_data, err := model.GetTrainingData(false)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	validation_data, err := model.GetValidationData(false)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	naive_bayes := model.NewGaussianNB()
+	random_forest := model.NewRandomForest(50, model.Gini, 1)
+	classifiers := []struct {
+		name       string
+		classifier model.Classifier
+	}{
+		{"Perceptron", model.NewPerceptron(epochs, 0.08, 1)},
+		{"Linear SVM", model.NewLinearSVM(epochs, lambda, 1)},
+		{"Perceptron Ensemble", model.NewEnsemble(10, epochs, model.ScoreAverage, 1)},
+		{"k-Nearest Neighbours", model.NewKNN(5, model.Euclidean, true)},
+		{"Gaussian Naive Bayes", naive_bayes},
+		{"Nearest Centroid", model.NewNearestCentroid(model.Euclidean)},
+		{"Decision Tree", model.NewDecisionTree(model.Gini, 10, 2, 5)},
+		{"Random Forest", random_forest},
+	}
+
+	fmt.Println("-----------------------------------------------------")
+	fmt.Println("Classifiers (on the validation data):")
+	for _, classifier := range classifiers {
+		if err := classifier.classifier.Fit(training_data); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		successes, errors := model.Evaluate(classifier.classifier, validation_data)
+		display.Accuracy(classifier.name, successes, errors)
+	}
+	display.ClassDistributions(feature_extraction.FeatureNames, naive_bayes.Means, naive_bayes.Variances)
+	display.FeatureImportances(feature_extraction.FeatureNames, random_forest.Importances)
+
 	test_file := "input_files/testing_data/unlabeled_digits.csv"
-	predicted_labels := model.GetPredictions(test_file, weights)
+	predictions, err := model.DefaultPipeline().GetScoredPredictions(test_file, &model.Perceptron{Weights: weights}, 3, false)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll("output_files", 0755); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	prediction_files := []struct {
+		file   string
+		format model.ExportFormat
+	}{
+		{"output_files/predictions.csv", model.CSV},
+		{"output_files/predictions.jsonl", model.JSONLines},
+		{"output_files/submission.csv", model.Submission},
+	}
+
+	predicted_labels := make([]int, len(predictions))
+	for i, prediction := range predictions {
+		predicted_labels[i] = prediction.Label
+	}
 
 	fmt.Println("-----------------------------------------------------")
 	fmt.Println("Predicted Labels:")
 	fmt.Println(predicted_labels)
+	for _, prediction_file := range prediction_files {
+		if err := model.WritePredictions(predictions, prediction_file.file, prediction_file.format); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("\tWritten to < %s >\n", prediction_file.file)
+	}
 }
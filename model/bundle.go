@@ -0,0 +1,148 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// Model is a trained classifier together with the pipeline that produced the
+// rows it was trained on. Saving and loading them together means that
+// predictions are always made from images normalized, binarized and reduced
+// to features exactly as they were during training.
+type Model struct {
+	Pipeline   Pipeline
+	Classifier Classifier
+}
+
+// savedModel is the form in which a Model is written to disk. Type names the
+// concrete type of the classifier so that it can be decoded.
+type savedModel struct {
+	Pipeline   Pipeline
+	Type       string
+	Classifier json.RawMessage
+}
+
+// newClassifiers returns an empty classifier of each type that a Model can
+// hold, keyed by the name under which it is saved.
+var newClassifiers = map[string]func() Classifier{
+	"perceptron":       func() Classifier { return &Perceptron{} },
+	"linear_svm":       func() Classifier { return &LinearSVM{} },
+	"knn":              func() Classifier { return &KNN{} },
+	"gaussian_nb":      func() Classifier { return &GaussianNB{} },
+	"nearest_centroid": func() Classifier { return &NearestCentroid{} },
+	"decision_tree":    func() Classifier { return &DecisionTree{} },
+	"random_forest":    func() Classifier { return &RandomForest{} },
+	"ensemble":         func() Classifier { return &Ensemble{} },
+}
+
+// getClassifierType returns the name under which the provided classifier is
+// saved, found by looking up its type in newClassifiers, or "" if it cannot be
+// saved.
+func getClassifierType(classifier Classifier) string {
+	for name, new_classifier := range newClassifiers {
+		if reflect.TypeOf(new_classifier()) == reflect.TypeOf(classifier) {
+			return name
+		}
+	}
+	return ""
+}
+
+// Fit builds the training data with the model's pipeline and fits the
+// model's classifier to it.
+func (m Model) Fit(verbose bool) error {
+	training_data, err := m.Pipeline.GetTrainingData(verbose)
+	if err != nil {
+		return err
+	}
+	return m.Classifier.Fit(training_data)
+}
+
+// Validate builds the validation data with the model's pipeline and returns
+// the total number of successful and unsuccessful predictions on it.
+func (m Model) Validate(verbose bool) (int, int, error) {
+	validation_data, err := m.Pipeline.GetValidationData(verbose)
+	if err != nil {
+		return 0, 0, err
+	}
+	successes, errors := Evaluate(m.Classifier, validation_data)
+	return successes, errors, nil
+}
+
+// PredictImage returns the predicted label for the provided greyscale image.
+func (m Model) PredictImage(image helpers.Image) (int, error) {
+	feature_values, err := m.Pipeline.GetFeatureValues(image)
+	if err != nil {
+		return 0, err
+	}
+	label := m.Classifier.Predict(append(feature_values, -1))
+	if label < 0 {
+		return 0, fmt.Errorf("classifier cannot score the image")
+	}
+	return label, nil
+}
+
+// GetPredictions returns the predicted labels for the images in the provided
+// file.
+func (m Model) GetPredictions(file string) ([]int, error) {
+	testing_data, err := m.Pipeline.GetTestingData(file, false)
+	if err != nil {
+		return nil, err
+	}
+
+	predictions := make([]int, len(testing_data))
+	for i, row := range testing_data {
+		predictions[i] = m.Classifier.Predict(row)
+		if predictions[i] < 0 {
+			return nil, fmt.Errorf("classifier cannot score row %d of %s", i, file)
+		}
+	}
+	return predictions, nil
+}
+
+// GetScoredPredictions returns the predictions for the images in the provided
+// file along with their top_k highest scoring classes, ready to be written by
+// WritePredictions.
+func (m Model) GetScoredPredictions(file string, top_k int) ([]Prediction, error) {
+	return m.Pipeline.GetScoredPredictions(file, m.Classifier, top_k, false)
+}
+
+// Save writes the pipeline and the trained classifier to the provided file as
+// JSON.
+func (m Model) Save(file string) error {
+	saved := savedModel{Pipeline: m.Pipeline, Type: getClassifierType(m.Classifier)}
+	if saved.Type == "" {
+		return fmt.Errorf("cannot save classifier of type %T", m.Classifier)
+	}
+
+	classifier, err := json.Marshal(m.Classifier)
+	if err != nil {
+		return err
+	}
+	saved.Classifier = classifier
+
+	return saveJSON(file, saved)
+}
+
+// LoadModel reads a model previously written by Model.Save from the provided
+// file.
+func LoadModel(file string) (Model, error) {
+	var saved savedModel
+	if err := loadJSON(file, &saved); err != nil {
+		return Model{}, err
+	}
+
+	new_classifier, ok := newClassifiers[saved.Type]
+	if !ok {
+		return Model{}, fmt.Errorf("%s: unknown classifier type %q", file, saved.Type)
+	}
+
+	classifier := new_classifier()
+	if err := json.Unmarshal(saved.Classifier, classifier); err != nil {
+		return Model{}, fmt.Errorf("%s: %v", file, err)
+	}
+
+	return Model{Pipeline: saved.Pipeline, Classifier: classifier}, nil
+}
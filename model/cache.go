@@ -0,0 +1,153 @@
+package model
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"project04_perceptron/go_rewrite/feature_extraction"
+)
+
+// CacheDirectory is the directory in which the feature values extracted from
+// each training and validation file are stored, so that later runs with the
+// same pipeline can reuse them instead of parsing the file and extracting the
+// features again. Set it to "" to disable the cache.
+var CacheDirectory = "input_files/feature_cache"
+
+// cacheMagic begins every cache file, identifying its format.
+var cacheMagic = [8]byte{'F', 'E', 'A', 'T', 'C', 'A', 'C', '1'}
+
+// getCacheKey returns the part of the cache key that describes how the
+// pipeline turns an image into feature values: every step but augmentation,
+// which does not change the values of the original images, and the version of
+// the feature extractors.
+func (pipeline Pipeline) getCacheKey() (string, error) {
+	pipeline.Augmentation = nil
+	description, err := json.Marshal(pipeline)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("features v%d %s", feature_extraction.FeatureVersion, description), nil
+}
+
+// getCacheFile returns the path of the file in CacheDirectory that holds the
+// feature values extracted from the provided source file as described by the
+// provided key. The path depends on a hash of the source file's content, read
+// a block at a time, so editing the file invalidates its cache entry.
+func getCacheFile(source, key string) (string, error) {
+	source_file, err := os.Open(source)
+	if err != nil {
+		return "", err
+	}
+	defer source_file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, source_file); err != nil {
+		return "", fmt.Errorf("%s: %v", source, err)
+	}
+	hash.Write([]byte{0})
+	hash.Write([]byte(key))
+
+	return filepath.Join(CacheDirectory, hex.EncodeToString(hash.Sum(nil))[:32]+".bin"), nil
+}
+
+// readCache returns the feature values and class labels stored in the
+// provided cache file. ok is false if the file does not exist or is not a
+// complete cache file, in which case the values must be extracted again.
+//
+// A cache file holds cacheMagic, the number of rows and the number of feature
+// values per row as little-endian uint64s, and then, for each row, its class
+// label as an int64 followed by its feature values as float64s.
+func readCache(file string) (feature_rows [][]float64, labels []int, ok bool) {
+	cache_file, err := os.Open(file)
+	if err != nil {
+		return nil, nil, false
+	}
+	defer cache_file.Close()
+	reader := bufio.NewReader(cache_file)
+
+	var magic [8]byte
+	var num_rows, num_cols uint64
+	if binary.Read(reader, binary.LittleEndian, &magic) != nil || magic != cacheMagic ||
+		binary.Read(reader, binary.LittleEndian, &num_rows) != nil ||
+		binary.Read(reader, binary.LittleEndian, &num_cols) != nil {
+		return nil, nil, false
+	}
+
+	// Check the header against the size of the file before allocating.
+	info, err := cache_file.Stat()
+	if err != nil || num_cols > uint64(info.Size()) || uint64(info.Size()) != 24+num_rows*8*(1+num_cols) {
+		return nil, nil, false
+	}
+
+	feature_rows, labels = make([][]float64, num_rows), make([]int, num_rows)
+	for i := range feature_rows {
+		var label int64
+		feature_rows[i] = make([]float64, num_cols)
+		if binary.Read(reader, binary.LittleEndian, &label) != nil ||
+			binary.Read(reader, binary.LittleEndian, feature_rows[i]) != nil {
+			return nil, nil, false
+		}
+		labels[i] = int(label)
+	}
+
+	// Trailing bytes mean the file was not written by writeCache.
+	if _, err := reader.ReadByte(); err != io.EOF {
+		return nil, nil, false
+	}
+
+	return feature_rows, labels, true
+}
+
+// writeCache stores the provided feature values and class labels in the
+// provided cache file in the format read by readCache. The file is written
+// under a temporary name and then renamed, so an interrupted run never leaves
+// a partial cache file behind.
+func writeCache(file string, feature_rows [][]float64, labels []int) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+
+	temporary, err := os.CreateTemp(filepath.Dir(file), "partial-*.bin")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+
+	num_cols := 0
+	if len(feature_rows) > 0 {
+		num_cols = len(feature_rows[0])
+	}
+
+	writer := bufio.NewWriter(temporary)
+	binary.Write(writer, binary.LittleEndian, cacheMagic)
+	binary.Write(writer, binary.LittleEndian, uint64(len(feature_rows)))
+	binary.Write(writer, binary.LittleEndian, uint64(num_cols))
+	for i, feature_values := range feature_rows {
+		if len(feature_values) != num_cols {
+			temporary.Close()
+			return fmt.Errorf("row %d has %d feature values, expected %d", i, len(feature_values), num_cols)
+		}
+		binary.Write(writer, binary.LittleEndian, int64(labels[i]))
+		binary.Write(writer, binary.LittleEndian, feature_values)
+	}
+
+	if err := writer.Flush(); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Chmod(0o644); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temporary.Name(), file)
+}
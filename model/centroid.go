@@ -0,0 +1,73 @@
+package model
+
+import (
+	"math"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// NearestCentroid is a nearest class centroid classifier. It summarizes each
+// class by the mean of its feature values and labels a new row with the class
+// whose centroid is closest under Metric.
+type NearestCentroid struct {
+	Metric    Metric
+	Centroids [][]float64 // mean feature values, per class
+}
+
+// NewNearestCentroid returns an unfitted nearest centroid classifier.
+func NewNearestCentroid(metric Metric) *NearestCentroid {
+	return &NearestCentroid{Metric: metric}
+}
+
+// Fit computes the centroid of each class from the provided training rows.
+// The last column of each row is the class label and the remaining columns are
+// the feature values.
+func (nc *NearestCentroid) Fit(rows [][]float64) error {
+	if err := nc.Metric.Validate(); err != nil {
+		return err
+	}
+
+	counts, means, err := getClassMeans(rows)
+	if err != nil {
+		return err
+	}
+
+	// A class with no rows has no centroid and can never be predicted.
+	for class := range means {
+		if counts[class] == 0 {
+			means[class] = nil
+		}
+	}
+
+	nc.Centroids = means
+	return nil
+}
+
+// Predict returns the predicted label for the provided feature values.
+func (nc *NearestCentroid) Predict(features []float64) int {
+	return helpers.ArgMax(nc.PredictScores(features))
+}
+
+// PredictScores returns the negated distance from the provided feature values
+// to the centroid of each class. Classes without a centroid score -Inf.
+func (nc *NearestCentroid) PredictScores(features []float64) []float64 {
+	scores := make([]float64, len(nc.Centroids))
+	for class, centroid := range nc.Centroids {
+		if centroid == nil {
+			scores[class] = math.Inf(-1)
+			continue
+		}
+		scores[class] = -nc.Metric.Distance(features, centroid)
+	}
+	return scores
+}
+
+// Save writes the classifier to the provided file as JSON.
+func (nc *NearestCentroid) Save(file string) error {
+	return saveJSON(file, nc)
+}
+
+// Load reads a classifier previously written by Save from the provided file.
+func (nc *NearestCentroid) Load(file string) error {
+	return loadJSON(file, nc)
+}
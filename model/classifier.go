@@ -0,0 +1,144 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+)
+
+// Classifier is implemented by every model in this package, so that code which
+// trains, evaluates or serves a model does not need to know which one it is.
+//
+// Rows passed to Fit are laid out like those returned by GetTrainingData: the
+// last column is the class label and the remaining columns are the feature
+// values. The features passed to Predict and PredictScores are such a row
+// without its class label, as returned by GetTestingData.
+type Classifier interface {
+	// Fit trains the classifier on the provided rows.
+	Fit(rows [][]float64) error
+
+	// Predict returns the predicted label for the provided feature values,
+	// or -1 if the classifier cannot score them, as when it is unfitted.
+	Predict(features []float64) int
+
+	// PredictScores returns one score per class for the provided feature
+	// values, where a higher score means the class is more likely. Predict
+	// returns the index of the highest score. It returns no scores if the
+	// classifier cannot score the feature values.
+	PredictScores(features []float64) []float64
+
+	// Save writes the trained classifier to the provided file.
+	Save(file string) error
+
+	// Load replaces the classifier with one previously written by Save.
+	Load(file string) error
+}
+
+var (
+	_ Classifier = (*Perceptron)(nil)
+	_ Classifier = (*LinearSVM)(nil)
+	_ Classifier = (*KNN)(nil)
+	_ Classifier = (*GaussianNB)(nil)
+	_ Classifier = (*NearestCentroid)(nil)
+	_ Classifier = (*DecisionTree)(nil)
+	_ Classifier = (*RandomForest)(nil)
+	_ Classifier = (*Ensemble)(nil)
+)
+
+// Evaluate will validate the provided classifier against the provided
+// validation results, returning the total number of successful and
+// unsuccessful predictions. A row the classifier cannot score, as every row
+// of an unfitted classifier, counts as unsuccessful.
+func Evaluate(classifier Classifier, validation_results [][]float64) (int, int) {
+	successes, errors := 0, 0
+
+	for _, row := range validation_results {
+		class_label := int(row[len(row)-1])
+		features := row[:len(row)-1]
+
+		if classifier.Predict(features) == class_label {
+			successes++
+		} else {
+			errors++
+		}
+	}
+
+	return successes, errors
+}
+
+// CrossValidate will split the provided rows into the provided number of
+// folds and, for each fold, fit a new classifier on the other folds and
+// evaluate it on that fold. It returns the total number of successful and
+// unsuccessful predictions over all folds.
+func CrossValidate(new_classifier func() Classifier, rows [][]float64, num_folds int) (int, int, error) {
+	if num_folds < 2 || num_folds > len(rows) {
+		return 0, 0, fmt.Errorf("number of folds must be between 2 and %d, got %d", len(rows), num_folds)
+	}
+
+	shuffled := make([][]float64, len(rows))
+	copy(shuffled, rows)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	total_successes, total_errors := 0, 0
+	for fold := 0; fold < num_folds; fold++ {
+		start, end := fold*len(shuffled)/num_folds, (fold+1)*len(shuffled)/num_folds
+
+		training_rows := make([][]float64, 0, len(shuffled)-(end-start))
+		training_rows = append(training_rows, shuffled[:start]...)
+		training_rows = append(training_rows, shuffled[end:]...)
+
+		classifier := new_classifier()
+		if err := classifier.Fit(training_rows); err != nil {
+			return 0, 0, err
+		}
+
+		successes, errors := Evaluate(classifier, shuffled[start:end])
+		total_successes += successes
+		total_errors += errors
+	}
+
+	return total_successes, total_errors, nil
+}
+
+// GetClassifierPredictions will return a slice of ints representing the
+// predicted labels for the provided file using the provided classifier.
+func GetClassifierPredictions(file string, classifier Classifier) ([]int, error) {
+	testing_results, err := GetTestingData(file, false)
+	if err != nil {
+		return nil, err
+	}
+
+	predictions := make([]int, len(testing_results))
+	for i, row := range testing_results {
+		predictions[i] = classifier.Predict(row)
+		if predictions[i] < 0 {
+			return nil, fmt.Errorf("classifier cannot score row %d of %s", i, file)
+		}
+	}
+
+	return predictions, nil
+}
+
+// saveJSON writes the provided value to the provided file as JSON.
+func saveJSON(file string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// loadJSON reads the JSON in the provided file into the provided value.
+func loadJSON(file string, value any) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+
+	"project04_perceptron/go_rewrite/helpers"
+	"project04_perceptron/go_rewrite/img_manip"
+)
+
+// The directories holding the training and validation files.
+const (
+	TrainingDirectory   = "input_files/training_data"
+	ValidationDirectory = "input_files/validation_data"
+)
+
+// Sample is the feature values of one image along with where the image came
+// from, so that rows can be traced back to their source after export.
+type Sample struct {
+	Source    string // file the image was read from
+	Row       int    // index of the image among the rows of Source, from 0
+	Augmented bool   // whether the image is a distorted copy made by augmentation
+	Label     int    // class label, or -1 if the source is unlabelled
+	Features  []float64
+}
+
+// DataSet is a list of samples whose feature values are named by
+// FeatureNames.
+type DataSet struct {
+	FeatureNames []string
+	Samples      []Sample
+}
+
+// GetDataSetFiles returns the ten handwritten_samples_%d.csv files in the
+// provided directory.
+func GetDataSetFiles(directory string) []string {
+	files := make([]string, 10)
+	for i := range files {
+		files[i] = fmt.Sprintf("%s/handwritten_samples_%d.csv", directory, i)
+	}
+	return files
+}
+
+// GetDataSet returns a sample for each image in the provided files, in order,
+// with the feature values produced by the pipeline. The first column of each
+// file is the class label if has_label is true. Training images are augmented
+// if the pipeline's Augmentation is set and has_label is true.
+func (pipeline Pipeline) GetDataSet(files []string, has_label bool, verbose bool) (DataSet, error) {
+	feature_names, err := pipeline.GetFeatureNames()
+	if err != nil {
+		return DataSet{}, err
+	}
+
+	cache_key, augmentation := "", (*img_manip.Augmentation)(nil)
+	if has_label {
+		if cache_key, err = pipeline.getCacheKey(); err != nil {
+			return DataSet{}, err
+		}
+		augmentation = pipeline.Augmentation
+	}
+
+	samples, err := getSamples(files, has_label, pipeline.GetFeatureValues, cache_key, augmentation, verbose)
+	if err != nil {
+		return DataSet{}, err
+	}
+
+	return DataSet{FeatureNames: feature_names, Samples: samples}, nil
+}
+
+// Rows returns the samples of the data set laid out like the rows returned by
+// GetTrainingData, so that they can be passed to TrainOnData or to the Fit
+// method of any Classifier. It returns an error if any sample is unlabelled.
+func (data_set DataSet) Rows() ([][]float64, error) {
+	rows := make([][]float64, len(data_set.Samples))
+	for i, sample := range data_set.Samples {
+		if sample.Label < 0 {
+			return nil, fmt.Errorf("sample %d (row %d of %s) has no class label", i, sample.Row, sample.Source)
+		}
+		if len(sample.Features) != len(data_set.FeatureNames) {
+			return nil, fmt.Errorf("sample %d (row %d of %s) has %d feature values, expected %d",
+				i, sample.Row, sample.Source, len(sample.Features), len(data_set.FeatureNames))
+		}
+		rows[i] = sample.getRow()
+	}
+	return rows, nil
+}
+
+// getRow returns the feature values of the sample followed by the threshold
+// value (-1) and the class label.
+func (sample Sample) getRow() []float64 {
+	row := make([]float64, 0, len(sample.Features)+2)
+	row = append(row, sample.Features...)
+	return append(row, -1, float64(sample.Label))
+}
+
+// getSamples returns a sample for each image in the provided files, in order,
+// holding the values returned by extract. See getDataSet for the meaning of
+// cache_key and augmentation; both are only used when has_label is true.
+func getSamples(files []string, has_label bool, extract func(helpers.Image) ([]float64, error), cache_key string, augmentation *img_manip.Augmentation, verbose bool) ([]Sample, error) {
+	samples := []Sample{}
+
+	var random *rand.Rand
+	if augmentation != nil {
+		random = rand.New(rand.NewSource(augmentation.Seed))
+	}
+
+	for _, filename := range files {
+		file_samples, err := getFileSamples(filename, has_label, extract, cache_key, augmentation, random, verbose)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, file_samples...)
+	}
+
+	return samples, nil
+}
+
+// getFileSamples returns the samples of getSamples for a single file. The file
+// is read one image at a time, and each image is extracted and augmented as
+// soon as it is parsed, so only the samples are kept in memory.
+func getFileSamples(filename string, has_label bool, extract func(helpers.Image) ([]float64, error), cache_key string, augmentation *img_manip.Augmentation, random *rand.Rand, verbose bool) ([]Sample, error) {
+	cache_file, feature_rows, labels, cached := "", [][]float64(nil), []int(nil), false
+	if cache_key != "" && CacheDirectory != "" {
+		var err error
+		cache_file, err = getCacheFile(filename, cache_key)
+		if err != nil {
+			return nil, err
+		}
+		feature_rows, labels, cached = readCache(cache_file)
+	}
+
+	if verbose && cached {
+		fmt.Printf("\tReading Cached Feature Values for < %s >...\n", filename)
+	} else if verbose {
+		fmt.Printf("\tComputing Feature Values in < %s >...\n", filename)
+	}
+
+	samples := []Sample{}
+
+	// Cached values of unaugmented images need no parsing at all.
+	if cached && augmentation == nil {
+		for j, feature_values := range feature_rows {
+			samples = append(samples, Sample{Source: filename, Row: j, Label: labels[j], Features: feature_values})
+		}
+		return samples, nil
+	}
+
+	reader, err := helpers.NewImageReader(filename, has_label)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	for j := 0; ; j++ {
+		image, label, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var feature_values []float64
+		if cached {
+			if j >= len(feature_rows) {
+				return nil, fmt.Errorf("%s: has more images than its cache", filename)
+			}
+			feature_values = feature_rows[j]
+		} else {
+			feature_values, err = extract(image)
+			if err != nil {
+				return nil, err
+			}
+			feature_rows, labels = append(feature_rows, feature_values), append(labels, label)
+		}
+		samples = append(samples, Sample{Source: filename, Row: reader.Row(), Label: label, Features: feature_values})
+
+		if augmentation == nil {
+			continue
+		}
+		for k := 0; k < augmentation.Multiplier; k++ {
+			augmented, err := img_manip.Augment(image, *augmentation, random)
+			if err != nil {
+				return nil, err
+			}
+			feature_values, err := extract(augmented)
+			if err != nil {
+				return nil, err
+			}
+			samples = append(samples, Sample{Source: filename, Row: reader.Row(), Augmented: true, Label: label, Features: feature_values})
+		}
+	}
+
+	if !cached && cache_file != "" {
+		if err := writeCache(cache_file, feature_rows, labels); err != nil {
+			return nil, err
+		}
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,274 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// Combiner names the way an Ensemble combines the predictions of its members.
+type Combiner string
+
+const (
+	MajorityVote Combiner = "vote"          // each member casts one vote
+	WeightedVote Combiner = "weighted_vote" // votes are weighted by held-out accuracy
+	ScoreAverage Combiner = "average"       // the members' softmax scores are averaged
+	Stacking     Combiner = "stacking"      // a perceptron learns from the members' scores
+)
+
+// EnsembleMember is one of the perceptrons of an Ensemble, along with the
+// feature columns it was trained on and the weight of its vote.
+type EnsembleMember struct {
+	Columns    []int // indices of the feature columns seen by the member
+	Weight     float64
+	Perceptron *Perceptron
+}
+
+// Ensemble is a collection of perceptrons, each trained with its own seed and
+// optionally on its own bootstrap sample of the rows and random subset of the
+// features, whose predictions are combined by Combiner.
+type Ensemble struct {
+	NumMembers      int
+	Epochs          int
+	LearningRate    float64 // eta η of each member
+	Combiner        Combiner
+	Bootstrap       bool    // train each member on a bootstrap sample of the rows
+	FeatureFraction float64 // share of the features seen by each member, or 0 for all
+	HoldoutFraction float64 // share of the rows held out to weight votes or fit the stacker
+	Seed            int64
+
+	Members []*EnsembleMember
+	Stacker *Perceptron `json:",omitempty"`
+}
+
+// NewEnsemble returns an unfitted ensemble of the provided number of
+// perceptrons, each trained on a bootstrap sample of the rows.
+func NewEnsemble(num_members, epochs int, combiner Combiner, seed int64) *Ensemble {
+	return &Ensemble{
+		NumMembers:      num_members,
+		Epochs:          epochs,
+		LearningRate:    0.08,
+		Combiner:        combiner,
+		Bootstrap:       true,
+		HoldoutFraction: 0.2,
+		Seed:            seed,
+	}
+}
+
+// Fit trains every member of the ensemble on the provided rows. When the
+// combiner needs them, HoldoutFraction of the rows are first set aside to
+// weight the members' votes or to train the stacking perceptron.
+func (ensemble *Ensemble) Fit(rows [][]float64) error {
+	if ensemble.NumMembers < 1 {
+		return fmt.Errorf("number of members must be at least 1, got %d", ensemble.NumMembers)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("no training rows provided")
+	}
+
+	random := rand.New(rand.NewSource(ensemble.Seed))
+
+	shuffled := make([][]float64, len(rows))
+	copy(shuffled, rows)
+	random.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	training_rows, holdout_rows := shuffled, [][]float64(nil)
+	if ensemble.Combiner == WeightedVote || ensemble.Combiner == Stacking {
+		num_holdout := int(ensemble.HoldoutFraction * float64(len(shuffled)))
+		if num_holdout < 1 || num_holdout >= len(shuffled) {
+			return fmt.Errorf("holdout fraction %f leaves no rows to hold out or train on", ensemble.HoldoutFraction)
+		}
+		training_rows, holdout_rows = shuffled[num_holdout:], shuffled[:num_holdout]
+	}
+
+	num_features := len(rows[0]) - 1
+	members := make([]*EnsembleMember, ensemble.NumMembers)
+	for m := range members {
+		sample := training_rows
+		if ensemble.Bootstrap {
+			sample = make([][]float64, len(training_rows))
+			for i := range sample {
+				sample[i] = training_rows[random.Intn(len(training_rows))]
+			}
+		}
+
+		member := &EnsembleMember{
+			Columns:    getFeatureSubset(random, num_features, ensemble.FeatureFraction),
+			Weight:     1,
+			Perceptron: NewPerceptron(ensemble.Epochs, ensemble.LearningRate, random.Int63()),
+		}
+		if err := member.Perceptron.Fit(selectColumns(sample, member.Columns)); err != nil {
+			return err
+		}
+		members[m] = member
+	}
+	ensemble.Members = members
+
+	switch ensemble.Combiner {
+	case WeightedVote:
+		for _, member := range members {
+			successes, _ := Evaluate(member.Perceptron, selectColumns(holdout_rows, member.Columns))
+			member.Weight = float64(successes) / float64(len(holdout_rows))
+		}
+	case Stacking:
+		stacked_rows := make([][]float64, len(holdout_rows))
+		for i, row := range holdout_rows {
+			stacked_rows[i] = append(ensemble.getStackedFeatures(row[:num_features]), row[num_features])
+		}
+		ensemble.Stacker = NewPerceptron(ensemble.Epochs, ensemble.LearningRate, random.Int63())
+		if err := ensemble.Stacker.Fit(stacked_rows); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Predict returns the predicted label for the provided feature values.
+func (ensemble *Ensemble) Predict(features []float64) int {
+	return helpers.ArgMax(ensemble.PredictScores(features))
+}
+
+// PredictScores returns the combined score of each class for the provided
+// feature values: the share of the (weighted) vote, the average softmax
+// probability, or the stacking perceptron's logits, depending on Combiner. It
+// returns no scores if the ensemble is unfitted.
+func (ensemble *Ensemble) PredictScores(features []float64) []float64 {
+	if len(ensemble.Members) == 0 {
+		return nil
+	}
+	if ensemble.Combiner == Stacking {
+		if ensemble.Stacker == nil {
+			return nil
+		}
+		return ensemble.Stacker.PredictScores(ensemble.getStackedFeatures(features))
+	}
+
+	combined := []float64{}
+	total := 0.0
+	for _, member := range ensemble.Members {
+		scores := member.Perceptron.PredictScores(selectFeatures(features, member.Columns))
+		for len(combined) < len(scores) {
+			combined = append(combined, 0)
+		}
+
+		switch ensemble.Combiner {
+		case ScoreAverage:
+			for class, p := range getSoftmax(scores) {
+				combined[class] += p
+			}
+			total++
+		case WeightedVote:
+			combined[helpers.ArgMax(scores)] += member.Weight
+			total += member.Weight
+		default:
+			combined[helpers.ArgMax(scores)]++
+			total++
+		}
+	}
+
+	if total > 0 {
+		for class := range combined {
+			combined[class] /= total
+		}
+	}
+
+	return combined
+}
+
+// Probabilities reports whether the scores of the ensemble are probabilities,
+// which they are unless Combiner is Stacking, whose scores are logits.
+func (ensemble *Ensemble) Probabilities() bool {
+	return ensemble.Combiner != Stacking
+}
+
+// Save writes the ensemble, including every member, to the provided file as
+// JSON.
+func (ensemble *Ensemble) Save(file string) error {
+	return saveJSON(file, ensemble)
+}
+
+// Load reads an ensemble previously written by Save from the provided file.
+func (ensemble *Ensemble) Load(file string) error {
+	return loadJSON(file, ensemble)
+}
+
+// getStackedFeatures returns the input of the stacking perceptron for the
+// provided feature values: the softmax scores of every member, followed by the
+// threshold value (-1).
+func (ensemble *Ensemble) getStackedFeatures(features []float64) []float64 {
+	stacked := []float64{}
+	for _, member := range ensemble.Members {
+		scores := member.Perceptron.PredictScores(selectFeatures(features, member.Columns))
+		stacked = append(stacked, getSoftmax(scores)...)
+	}
+	return append(stacked, -1)
+}
+
+// getFeatureSubset returns the sorted indices of a random subset of the
+// provided number of feature columns holding the provided fraction of them.
+// The last column, the threshold value, is always included.
+func getFeatureSubset(random *rand.Rand, num_features int, fraction float64) []int {
+	if fraction <= 0 || fraction >= 1 {
+		fraction = 1
+	}
+
+	num_chosen := int(math.Max(1, math.Round(fraction*float64(num_features-1))))
+	chosen := make([]bool, num_features)
+	for _, column := range random.Perm(num_features - 1)[:min(num_chosen, num_features-1)] {
+		chosen[column] = true
+	}
+	chosen[num_features-1] = true
+
+	columns := []int{}
+	for column := range chosen {
+		if chosen[column] {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// selectFeatures returns the provided feature values at the provided columns.
+func selectFeatures(features []float64, columns []int) []float64 {
+	selected := make([]float64, len(columns))
+	for i, column := range columns {
+		selected[i] = features[column]
+	}
+	return selected
+}
+
+// selectColumns returns the provided rows restricted to the provided feature
+// columns, keeping the class label of each row.
+func selectColumns(rows [][]float64, columns []int) [][]float64 {
+	selected := make([][]float64, len(rows))
+	for i, row := range rows {
+		selected[i] = append(selectFeatures(row, columns), row[len(row)-1])
+	}
+	return selected
+}
+
+// getSoftmax returns the provided scores normalized into probabilities that
+// sum to 1, or no probabilities if there are no scores.
+func getSoftmax(scores []float64) []float64 {
+	if len(scores) == 0 {
+		return nil
+	}
+
+	max_score := scores[helpers.ArgMax(scores)]
+
+	probabilities := make([]float64, len(scores))
+	total := 0.0
+	for i, score := range scores {
+		probabilities[i] = math.Exp(score - max_score)
+		total += probabilities[i]
+	}
+	for i := range probabilities {
+		probabilities[i] /= total
+	}
+	return probabilities
+}
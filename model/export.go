@@ -0,0 +1,539 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ExportFormat names a file format for ExportDataSet and ImportDataSet.
+type ExportFormat string
+
+const (
+	CSV       ExportFormat = "csv"   // a header row of column names, then one row per sample
+	JSONLines ExportFormat = "jsonl" // one JSON object per sample, keyed by column name
+	ARFF      ExportFormat = "arff"  // Weka's attribute-relation file format
+)
+
+// The columns of an exported data set that are not feature values. Every other
+// column holds the feature value of the same name.
+const (
+	sourceColumn    = "source"
+	rowColumn       = "row"
+	augmentedColumn = "augmented"
+	labelColumn     = "label"
+)
+
+// GetExportFormat returns the format matching the extension of the provided
+// file name: .csv, .jsonl or .arff.
+func GetExportFormat(file string) (ExportFormat, error) {
+	switch {
+	case strings.HasSuffix(file, ".csv"):
+		return CSV, nil
+	case strings.HasSuffix(file, ".jsonl"):
+		return JSONLines, nil
+	case strings.HasSuffix(file, ".arff"):
+		return ARFF, nil
+	}
+	return "", fmt.Errorf("%s: unknown export format, expected a .csv, .jsonl or .arff file", file)
+}
+
+// ExportDataSet writes the provided data set to the provided file in the
+// provided format. Each sample is written as its source file, row index,
+// whether it is augmented, its feature values in the order of FeatureNames and
+// its class label, which is left empty (null in JSON Lines, ? in ARFF) if the
+// sample is unlabelled. Feature values are written with as many digits as are
+// needed to read them back exactly.
+func ExportDataSet(data_set DataSet, file string, format ExportFormat) error {
+	for _, name := range data_set.FeatureNames {
+		if isReservedColumn(name) {
+			return fmt.Errorf("feature name %q is reserved for a column of the export", name)
+		}
+	}
+	for i, sample := range data_set.Samples {
+		if len(sample.Features) != len(data_set.FeatureNames) {
+			return fmt.Errorf("sample %d (row %d of %s) has %d feature values, expected %d",
+				i, sample.Row, sample.Source, len(sample.Features), len(data_set.FeatureNames))
+		}
+	}
+
+	output := bytes.Buffer{}
+	var err error
+	switch format {
+	case CSV:
+		err = writeCSV(&output, data_set)
+	case JSONLines:
+		err = writeJSONLines(&output, data_set)
+	case ARFF:
+		err = writeARFF(&output, data_set)
+	default:
+		err = fmt.Errorf("unknown export format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, output.Bytes(), 0644)
+}
+
+// ImportDataSet reads a data set from the provided file in the provided
+// format, such as one written by ExportDataSet or built by another tool. The
+// source, row, augmented and label columns are optional; every other column
+// must hold a number for every sample and becomes a feature. Samples without a
+// source are given the file's name and their index in it, and samples without
+// a label are unlabelled.
+func ImportDataSet(file string, format ExportFormat) (DataSet, error) {
+	input, err := os.Open(file)
+	if err != nil {
+		return DataSet{}, err
+	}
+	defer input.Close()
+
+	var columns []string
+	var records [][]string
+	switch format {
+	case CSV:
+		columns, records, err = readCSV(input)
+	case JSONLines:
+		columns, records, err = readJSONLines(input)
+	case ARFF:
+		columns, records, err = readARFF(input)
+	default:
+		err = fmt.Errorf("unknown export format %q", format)
+	}
+	if err != nil {
+		return DataSet{}, fmt.Errorf("%s: %v", file, err)
+	}
+
+	data_set, err := newDataSet(file, columns, records)
+	if err != nil {
+		return DataSet{}, fmt.Errorf("%s: %v", file, err)
+	}
+	return data_set, nil
+}
+
+// isReservedColumn reports whether the provided column name is one of the
+// columns of an export that is not a feature value.
+func isReservedColumn(name string) bool {
+	return name == sourceColumn || name == rowColumn || name == augmentedColumn || name == labelColumn
+}
+
+// getExportColumns returns the names of the columns of the provided data set
+// when exported, in order.
+func getExportColumns(data_set DataSet) []string {
+	columns := []string{sourceColumn, rowColumn, augmentedColumn}
+	columns = append(columns, data_set.FeatureNames...)
+	return append(columns, labelColumn)
+}
+
+// getExportRecord returns the values of the columns of the provided sample
+// when exported, in the order of getExportColumns. An unlabelled sample has an
+// empty label.
+func getExportRecord(sample Sample) []string {
+	record := []string{sample.Source, strconv.Itoa(sample.Row), strconv.FormatBool(sample.Augmented)}
+	for _, value := range sample.Features {
+		record = append(record, strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	label := ""
+	if sample.Label >= 0 {
+		label = strconv.Itoa(sample.Label)
+	}
+	return append(record, label)
+}
+
+// writeCSV writes the provided data set as CSV.
+func writeCSV(output io.Writer, data_set DataSet) error {
+	writer := csv.NewWriter(output)
+	writer.Write(getExportColumns(data_set))
+	for _, sample := range data_set.Samples {
+		writer.Write(getExportRecord(sample))
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// readCSV returns the header and the remaining rows of the provided CSV.
+func readCSV(input io.Reader) ([]string, [][]string, error) {
+	reader := csv.NewReader(input)
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("no header row")
+	}
+	return records[0], records[1:], nil
+}
+
+// writeJSONLines writes the provided data set as one JSON object per sample,
+// with the keys in the order of getExportColumns. JSON has no representation
+// of NaN or infinity, so a sample holding one is an error.
+func writeJSONLines(output io.Writer, data_set DataSet) error {
+	writer := bufio.NewWriter(output)
+	columns := getExportColumns(data_set)
+	keys := make([][]byte, len(columns))
+	for i, column := range columns {
+		keys[i], _ = json.Marshal(column)
+	}
+
+	for i, sample := range data_set.Samples {
+		source, _ := json.Marshal(sample.Source)
+		fmt.Fprintf(writer, "{%s:%s,%s:%d,%s:%t", keys[0], source, keys[1], sample.Row, keys[2], sample.Augmented)
+		for j, value := range sample.Features {
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				return fmt.Errorf("sample %d (row %d of %s) has feature %q of %v, which JSON cannot represent",
+					i, sample.Row, sample.Source, data_set.FeatureNames[j], value)
+			}
+			fmt.Fprintf(writer, ",%s:%s", keys[3+j], strconv.FormatFloat(value, 'g', -1, 64))
+		}
+		if sample.Label >= 0 {
+			fmt.Fprintf(writer, ",%s:%d}\n", keys[len(keys)-1], sample.Label)
+		} else {
+			fmt.Fprintf(writer, ",%s:null}\n", keys[len(keys)-1])
+		}
+	}
+	return writer.Flush()
+}
+
+// readJSONLines returns the keys of the first object of the provided JSON
+// Lines, in order, and the values of every object in the same order. Every
+// object must have the same keys. Numbers and booleans are returned as their
+// JSON text, and null as an empty string.
+func readJSONLines(input io.Reader) ([]string, [][]string, error) {
+	columns, records := []string(nil), [][]string{}
+	column_indices := map[string]int{}
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(nil, 64*1024*1024)
+	for line_number := 1; scanner.Scan(); line_number++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		keys, values, err := readJSONObject(scanner.Bytes())
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", line_number, err)
+		}
+
+		if columns == nil {
+			columns = keys
+			for i, key := range keys {
+				if _, ok := column_indices[key]; ok {
+					return nil, nil, fmt.Errorf("line %d: key %q appears more than once", line_number, key)
+				}
+				column_indices[key] = i
+			}
+		}
+		if len(keys) != len(columns) {
+			return nil, nil, fmt.Errorf("line %d: object has %d keys, expected %d", line_number, len(keys), len(columns))
+		}
+
+		record := make([]string, len(columns))
+		for i, key := range keys {
+			index, ok := column_indices[key]
+			if !ok {
+				return nil, nil, fmt.Errorf("line %d: unexpected key %q", line_number, key)
+			}
+			record[index] = values[i]
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	if columns == nil {
+		return nil, nil, fmt.Errorf("no objects")
+	}
+	return columns, records, nil
+}
+
+// readJSONObject returns the keys of the provided flat JSON object in order,
+// and their values as described by readJSONLines.
+func readJSONObject(line []byte) ([]string, []string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, nil, fmt.Errorf("expected a JSON object")
+	}
+
+	keys, values := []string{}, []string{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := token.(string)
+
+		token, err = decoder.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch value := token.(type) {
+		case string:
+			values = append(values, value)
+		case json.Number:
+			values = append(values, value.String())
+		case bool:
+			values = append(values, strconv.FormatBool(value))
+		case nil:
+			values = append(values, "")
+		default:
+			return nil, nil, fmt.Errorf("value of %q is not a string, number, boolean or null", key)
+		}
+		keys = append(keys, key)
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, nil, fmt.Errorf("unexpected data after the object")
+	}
+	return keys, values, nil
+}
+
+// writeARFF writes the provided data set as a Weka ARFF file. The source is a
+// string attribute, the row a numeric one, augmented a {false,true} nominal
+// one and each feature a numeric one. The label is nominal, with a value for
+// every digit and for any larger label in the data set, so that files exported
+// from different subsets of the data declare the same classes.
+func writeARFF(output io.Writer, data_set DataSet) error {
+	writer := bufio.NewWriter(output)
+
+	max_label := 9
+	for _, sample := range data_set.Samples {
+		max_label = max(max_label, sample.Label)
+	}
+	labels := make([]string, max_label+1)
+	for i := range labels {
+		labels[i] = strconv.Itoa(i)
+	}
+
+	fmt.Fprintf(writer, "@RELATION features\n\n")
+	fmt.Fprintf(writer, "@ATTRIBUTE %s STRING\n", sourceColumn)
+	fmt.Fprintf(writer, "@ATTRIBUTE %s NUMERIC\n", rowColumn)
+	fmt.Fprintf(writer, "@ATTRIBUTE %s {false,true}\n", augmentedColumn)
+	for _, name := range data_set.FeatureNames {
+		fmt.Fprintf(writer, "@ATTRIBUTE %s NUMERIC\n", quoteARFF(name))
+	}
+	fmt.Fprintf(writer, "@ATTRIBUTE %s {%s}\n\n@DATA\n", labelColumn, strings.Join(labels, ","))
+
+	for _, sample := range data_set.Samples {
+		record := getExportRecord(sample)
+		record[0] = quoteARFF(record[0])
+		if record[len(record)-1] == "" {
+			record[len(record)-1] = "?"
+		}
+		fmt.Fprintln(writer, strings.Join(record, ","))
+	}
+	return writer.Flush()
+}
+
+// readARFF returns the attribute names of the provided ARFF file and the
+// values of each of its data rows, with missing values (?) as empty strings.
+// Only string attributes named source, nominal attributes named augmented or
+// label, and numeric attributes are supported; sparse data is not.
+func readARFF(input io.Reader) ([]string, [][]string, error) {
+	columns, records := []string{}, [][]string{}
+	in_data := false
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(nil, 64*1024*1024)
+	for line_number := 1; scanner.Scan(); line_number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "%") {
+			continue
+		}
+
+		if in_data {
+			if strings.HasPrefix(line, "{") {
+				return nil, nil, fmt.Errorf("line %d: sparse ARFF data is not supported", line_number)
+			}
+			values, err := splitARFF(line)
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %v", line_number, err)
+			}
+			for i := range values {
+				if values[i] == "?" {
+					values[i] = ""
+				}
+			}
+			records = append(records, values)
+			continue
+		}
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(keyword) {
+		case "@RELATION":
+		case "@ATTRIBUTE":
+			fields, err := splitARFFAttribute(strings.TrimSpace(rest))
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %v", line_number, err)
+			}
+			name, kind := fields[0], strings.ToUpper(fields[1])
+			switch {
+			case kind == "NUMERIC" || kind == "REAL" || kind == "INTEGER":
+			case kind == "STRING" && name == sourceColumn:
+			case strings.HasPrefix(kind, "{") && (name == augmentedColumn || name == labelColumn):
+			default:
+				return nil, nil, fmt.Errorf("line %d: attribute %q has unsupported type %s", line_number, name, fields[1])
+			}
+			columns = append(columns, name)
+		case "@DATA":
+			in_data = true
+		default:
+			return nil, nil, fmt.Errorf("line %d: unexpected %q", line_number, keyword)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	if !in_data {
+		return nil, nil, fmt.Errorf("no @DATA section")
+	}
+	return columns, records, nil
+}
+
+// quoteARFF returns the provided string quoted for an ARFF file, if it needs
+// to be.
+func quoteARFF(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t,'\"\\%{}?") {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + replacer.Replace(value) + "'"
+}
+
+// splitARFF splits the provided ARFF data row into its values, removing any
+// quotes around them.
+func splitARFF(line string) ([]string, error) {
+	values := []string{}
+	for {
+		value, rest, err := cutARFFValue(strings.TrimLeft(line, " \t"))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			return values, nil
+		}
+		if rest[0] != ',' {
+			return nil, fmt.Errorf("expected a comma after value %d", len(values))
+		}
+		line = rest[1:]
+	}
+}
+
+// splitARFFAttribute splits the provided ARFF attribute declaration, without
+// its @ATTRIBUTE keyword, into the attribute's name and type.
+func splitARFFAttribute(declaration string) ([]string, error) {
+	name, rest, err := cutARFFValue(declaration)
+	if err != nil {
+		return nil, err
+	}
+	kind := strings.TrimSpace(rest)
+	if name == "" || kind == "" {
+		return nil, fmt.Errorf("expected an attribute name and type")
+	}
+	return []string{name, kind}, nil
+}
+
+// cutARFFValue returns the value at the start of the provided text, unquoted,
+// and the text after it. An unquoted value ends at a comma or whitespace.
+func cutARFFValue(text string) (string, string, error) {
+	if text == "" || (text[0] != '\'' && text[0] != '"') {
+		end := strings.IndexAny(text, ", \t")
+		if end < 0 {
+			end = len(text)
+		}
+		return text[:end], text[end:], nil
+	}
+
+	quote, value := text[0], strings.Builder{}
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			if i+1 < len(text) {
+				i++
+				value.WriteByte(text[i])
+			}
+		case quote:
+			return value.String(), text[i+1:], nil
+		default:
+			value.WriteByte(text[i])
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted value")
+}
+
+// newDataSet returns the data set held by the provided columns and records,
+// read from the provided file, as described by ImportDataSet.
+func newDataSet(file string, columns []string, records [][]string) (DataSet, error) {
+	data_set := DataSet{FeatureNames: []string{}, Samples: make([]Sample, len(records))}
+
+	reserved, feature_columns := map[string]int{}, []int{}
+	for i, column := range columns {
+		if !isReservedColumn(column) {
+			data_set.FeatureNames = append(data_set.FeatureNames, column)
+			feature_columns = append(feature_columns, i)
+		} else if _, ok := reserved[column]; ok {
+			return DataSet{}, fmt.Errorf("column %q appears more than once", column)
+		} else {
+			reserved[column] = i
+		}
+	}
+
+	for i, record := range records {
+		if len(record) != len(columns) {
+			return DataSet{}, fmt.Errorf("sample %d has %d values, expected %d", i, len(record), len(columns))
+		}
+
+		sample := Sample{Source: file, Row: i, Label: -1, Features: make([]float64, len(feature_columns))}
+		if index, ok := reserved[sourceColumn]; ok {
+			sample.Source = record[index]
+		}
+		if index, ok := reserved[rowColumn]; ok {
+			row, err := strconv.Atoi(record[index])
+			if err != nil || row < 0 {
+				return DataSet{}, fmt.Errorf("sample %d has row %q, expected a non-negative integer", i, record[index])
+			}
+			sample.Row = row
+		}
+		if index, ok := reserved[augmentedColumn]; ok && record[index] != "" {
+			augmented, err := strconv.ParseBool(record[index])
+			if err != nil {
+				return DataSet{}, fmt.Errorf("sample %d has augmented %q, expected true or false", i, record[index])
+			}
+			sample.Augmented = augmented
+		}
+		if index, ok := reserved[labelColumn]; ok && record[index] != "" {
+			label, err := strconv.Atoi(record[index])
+			if err != nil || label < 0 {
+				return DataSet{}, fmt.Errorf("sample %d has label %q, expected a non-negative integer", i, record[index])
+			}
+			sample.Label = label
+		}
+
+		for j, index := range feature_columns {
+			value, err := strconv.ParseFloat(strings.TrimSpace(record[index]), 64)
+			if err != nil {
+				return DataSet{}, fmt.Errorf("sample %d has %q of %q, expected a number", i, columns[index], record[index])
+			}
+			sample.Features[j] = value
+		}
+
+		data_set.Samples[i] = sample
+	}
+
+	return data_set, nil
+}
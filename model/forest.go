@@ -0,0 +1,150 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// RandomForest is a bagged ensemble of decision trees. Each tree is grown on a
+// bootstrap sample of the training rows and considers a random subset of the
+// features at each split; the forest predicts by averaging the class
+// distributions of its trees.
+type RandomForest struct {
+	NumTrees        int
+	Criterion       Criterion
+	MaxDepth        int   // maximum depth of each tree, or 0 for no limit
+	MinSamplesSplit int   // minimum number of rows a node needs to be split
+	MinSamplesLeaf  int   // minimum number of rows on each side of a split
+	MaxFeatures     int   // features considered at each split, or 0 for sqrt
+	Seed            int64 // seeds the bootstrap samples and feature choices
+
+	Trees       []*DecisionTree
+	Importances []float64 // mean impurity decrease due to each feature
+}
+
+// NewRandomForest returns an unfitted random forest of the provided number of
+// trees, each grown without a depth limit.
+func NewRandomForest(num_trees int, criterion Criterion, seed int64) *RandomForest {
+	return &RandomForest{
+		NumTrees:        num_trees,
+		Criterion:       criterion,
+		MinSamplesSplit: 2,
+		MinSamplesLeaf:  1,
+		Seed:            seed,
+	}
+}
+
+// Fit grows the trees of the forest in parallel from the provided training
+// rows. The last column of each row is the class label and the remaining
+// columns are the feature values.
+func (forest *RandomForest) Fit(rows [][]float64) error {
+	if forest.NumTrees < 1 {
+		return fmt.Errorf("number of trees must be at least 1, got %d", forest.NumTrees)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("no training rows provided")
+	}
+
+	num_features := len(rows[0]) - 1
+	max_features := forest.MaxFeatures
+	if max_features <= 0 {
+		max_features = int(math.Max(1, math.Round(math.Sqrt(float64(num_features)))))
+	}
+
+	// Draw every bootstrap sample up front so the forest does not depend on
+	// the order in which the goroutines run.
+	random := rand.New(rand.NewSource(forest.Seed))
+	samples := make([][][]float64, forest.NumTrees)
+	seeds := make([]int64, forest.NumTrees)
+	for t := range samples {
+		samples[t] = make([][]float64, len(rows))
+		for i := range samples[t] {
+			samples[t][i] = rows[random.Intn(len(rows))]
+		}
+		seeds[t] = random.Int63()
+	}
+
+	trees := make([]*DecisionTree, forest.NumTrees)
+	errs := make([]error, forest.NumTrees)
+
+	semaphore := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for t := range trees {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(t int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			tree := NewDecisionTree(forest.Criterion, forest.MaxDepth, forest.MinSamplesSplit, forest.MinSamplesLeaf)
+			tree.MaxFeatures = max_features
+			tree.Seed = seeds[t]
+			errs[t] = tree.Fit(samples[t])
+			trees[t] = tree
+		}(t)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	importances := make([]float64, num_features)
+	for _, tree := range trees {
+		for i, importance := range tree.Importances {
+			importances[i] += importance / float64(len(trees))
+		}
+	}
+
+	forest.Trees, forest.Importances = trees, importances
+	return nil
+}
+
+// Predict returns the predicted label for the provided feature values, or -1
+// if the forest is unfitted.
+func (forest *RandomForest) Predict(features []float64) int {
+	return helpers.ArgMax(forest.PredictScores(features))
+}
+
+// PredictScores returns the average of the class distributions predicted by
+// each tree for the provided feature values, or no scores if the forest is
+// unfitted.
+func (forest *RandomForest) PredictScores(features []float64) []float64 {
+	if len(forest.Trees) == 0 {
+		return nil
+	}
+
+	distribution := []float64{}
+	for _, tree := range forest.Trees {
+		for class, p := range tree.PredictScores(features) {
+			for class >= len(distribution) {
+				distribution = append(distribution, 0)
+			}
+			distribution[class] += p / float64(len(forest.Trees))
+		}
+	}
+	return distribution
+}
+
+// Probabilities reports that the scores of the forest are averaged class
+// distributions, which are probabilities.
+func (forest *RandomForest) Probabilities() bool {
+	return true
+}
+
+// Save writes the forest, including every tree, to the provided file as JSON.
+func (forest *RandomForest) Save(file string) error {
+	return saveJSON(file, forest)
+}
+
+// Load reads a forest previously written by Save from the provided file.
+func (forest *RandomForest) Load(file string) error {
+	return loadJSON(file, forest)
+}
@@ -0,0 +1,255 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// Metric names the distance function used to compare two feature vectors.
+type Metric string
+
+const (
+	Euclidean Metric = "euclidean"
+	Manhattan Metric = "manhattan"
+	Cosine    Metric = "cosine"
+)
+
+// Validate returns an error unless the metric is one of the known metrics.
+func (metric Metric) Validate() error {
+	switch metric {
+	case Euclidean, Manhattan, Cosine:
+		return nil
+	}
+	return fmt.Errorf("unknown metric %q", metric)
+}
+
+// Distance returns the distance between the provided vectors under the metric.
+// It panics if the metric is unknown; Fit rejects such metrics beforehand.
+func (metric Metric) Distance(a, b []float64) float64 {
+	switch metric {
+	case Manhattan:
+		sum := 0.0
+		for i := range a {
+			sum += math.Abs(a[i] - b[i])
+		}
+		return sum
+	case Cosine:
+		dot, norm_a, norm_b := 0.0, 0.0, 0.0
+		for i := range a {
+			dot += a[i] * b[i]
+			norm_a += a[i] * a[i]
+			norm_b += b[i] * b[i]
+		}
+		if norm_a == 0 || norm_b == 0 {
+			return 1
+		}
+		return 1 - dot/math.Sqrt(norm_a*norm_b)
+	case Euclidean:
+		sum := 0.0
+		for i := range a {
+			difference := a[i] - b[i]
+			sum += difference * difference
+		}
+		return math.Sqrt(sum)
+	}
+	panic(fmt.Sprintf("unknown metric %q", metric))
+}
+
+// KNN is a k-nearest-neighbour classifier. It stores every training row and
+// labels a new row by a vote among the K stored rows closest to it.
+//
+// KNN works on any rows laid out like those returned by GetTrainingData, so it
+// can be fit on the extracted features or on the raw pixels returned by
+// GetPixelTrainingData.
+type KNN struct {
+	K        int
+	Metric   Metric
+	Weighted bool // weight each vote by the inverse of the neighbour's distance
+
+	Features   [][]float64 // feature values of each training row
+	Labels     []int       // class label of each training row
+	NumClasses int         // one more than the largest class label
+}
+
+// neighbour is a training row that is a candidate for the K nearest.
+type neighbour struct {
+	distance float64
+	label    int
+}
+
+// NewKNN returns an unfitted k-nearest-neighbour classifier.
+func NewKNN(k int, metric Metric, weighted bool) *KNN {
+	return &KNN{K: k, Metric: metric, Weighted: weighted}
+}
+
+// Fit stores the provided training rows. The last column of each row is the
+// class label and the remaining columns are the feature values.
+func (knn *KNN) Fit(rows [][]float64) error {
+	if knn.K < 1 {
+		return fmt.Errorf("k must be at least 1, got %d", knn.K)
+	}
+	if err := knn.Metric.Validate(); err != nil {
+		return err
+	}
+	if len(rows) < knn.K {
+		return fmt.Errorf("need at least %d training rows, got %d", knn.K, len(rows))
+	}
+	num_classes, err := getNumClasses(rows)
+	if err != nil {
+		return err
+	}
+	for i, row := range rows {
+		if len(row) != len(rows[0]) {
+			return fmt.Errorf("row %d has %d columns, expected %d", i, len(row), len(rows[0]))
+		}
+	}
+
+	knn.Features = make([][]float64, len(rows))
+	knn.Labels = make([]int, len(rows))
+	for i, row := range rows {
+		knn.Features[i] = row[:len(row)-1]
+		knn.Labels[i] = int(row[len(row)-1])
+	}
+	knn.NumClasses = num_classes
+
+	return nil
+}
+
+// Predict returns the predicted label for the provided feature values. It is
+// safe to call Predict from several goroutines at once.
+func (knn *KNN) Predict(features []float64) int {
+	return helpers.ArgMax(knn.PredictScores(features))
+}
+
+// PredictScores returns the share of the vote won by each class among the K
+// nearest training rows to the provided feature values, or no scores if the
+// classifier is unfitted or the feature values do not match the training rows
+// in length.
+func (knn *KNN) PredictScores(features []float64) []float64 {
+	if len(knn.Features) == 0 || len(features) != len(knn.Features[0]) {
+		return nil
+	}
+
+	nearest := knn.getNearest(features)
+
+	votes := make([]float64, knn.NumClasses)
+	total := 0.0
+	for _, n := range nearest {
+		vote := 1.0
+		if knn.Weighted {
+			vote = 1 / (n.distance + 1e-9)
+		}
+		votes[n.label] += vote
+		total += vote
+	}
+
+	for class := range votes {
+		votes[class] /= total
+	}
+
+	return votes
+}
+
+// Probabilities reports that the scores of the classifier are shares of the
+// vote, which are probabilities.
+func (knn *KNN) Probabilities() bool {
+	return true
+}
+
+// Save writes the classifier, including every training row, to the provided
+// file as JSON.
+func (knn *KNN) Save(file string) error {
+	return saveJSON(file, knn)
+}
+
+// Load reads a classifier previously written by Save from the provided file.
+func (knn *KNN) Load(file string) error {
+	return loadJSON(file, knn)
+}
+
+// PredictAll returns the predicted label for each of the provided rows,
+// spreading the brute-force search over one goroutine per CPU. Rows may
+// include a trailing class label, which is ignored. It returns an error if the
+// classifier is unfitted or a row does not match the training rows in length.
+func (knn *KNN) PredictAll(rows [][]float64) ([]int, error) {
+	if len(knn.Features) == 0 {
+		return nil, fmt.Errorf("k-nearest-neighbour classifier is not fitted")
+	}
+
+	predictions := make([]int, len(rows))
+	width := len(knn.Features[0])
+	for i, row := range rows {
+		if len(row) != width && len(row) != width+1 {
+			return nil, fmt.Errorf("row %d has %d columns, expected %d, or %d with a class label", i, len(row), width, width+1)
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				predictions[i] = knn.Predict(rows[i][:width])
+			}
+		}()
+	}
+
+	for i := range rows {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return predictions, nil
+}
+
+// Validate returns the total number of successful and unsuccessful
+// predictions for the provided validation rows.
+func (knn *KNN) Validate(validation_results [][]float64) (int, int, error) {
+	successes, errors := 0, 0
+
+	predictions, err := knn.PredictAll(validation_results)
+	if err != nil {
+		return 0, 0, err
+	}
+	for i, row := range validation_results {
+		if predictions[i] == int(row[len(row)-1]) {
+			successes++
+		} else {
+			errors++
+		}
+	}
+
+	return successes, errors, nil
+}
+
+// getNearest returns the K stored rows closest to the provided features,
+// ordered from nearest to farthest.
+func (knn *KNN) getNearest(features []float64) []neighbour {
+	nearest := make([]neighbour, 0, knn.K)
+
+	for i, stored := range knn.Features {
+		distance := knn.Metric.Distance(features, stored)
+		if len(nearest) == knn.K && distance >= nearest[knn.K-1].distance {
+			continue
+		}
+
+		// Insert in order, dropping the farthest once K are held.
+		if len(nearest) < knn.K {
+			nearest = append(nearest, neighbour{})
+		}
+		j := len(nearest) - 1
+		for ; j > 0 && nearest[j-1].distance > distance; j-- {
+			nearest[j] = nearest[j-1]
+		}
+		nearest[j] = neighbour{distance, knn.Labels[i]}
+	}
+
+	return nearest
+}
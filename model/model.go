@@ -8,6 +8,7 @@ import (
 
 	"project04_perceptron/go_rewrite/feature_extraction"
 	"project04_perceptron/go_rewrite/helpers"
+	"project04_perceptron/go_rewrite/img_manip"
 )
 
 // GetTrainingData returns a slice of slices of floats representing the training
@@ -16,66 +17,7 @@ import (
 // The second to last column represents the threshold value (-1) for the image.
 // The last column represents the class label for the image.
 func GetTrainingData(verbose bool) ([][]float64, error) {
-	if verbose {
-		fmt.Println("------------------------------------------------")
-		fmt.Println("Building Training Set...")
-	}
-
-	training_data := [][]float64{}
-	class_labels := [][]int{}
-
-	num_files := 10
-	for i := 0; i < num_files; i++ {
-		filename := fmt.Sprintf("input_files/training_data/handwritten_samples_%d.csv", i)
-		images, labels, err := helpers.ExtractImages(filename, true)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, label := range labels {
-			class_labels = append(class_labels, []int{label})
-		}
-
-		if verbose {
-			fmt.Printf("\tComputing Feature Values in < %s >...\n", filename)
-		}
-
-		for _, image := range images {
-			binary_image := helpers.GetBlackWhite(image, 128)
-			feature_values := feature_extraction.GetFeatureValues(binary_image)
-			training_data = append(training_data, feature_values)
-		}
-	}
-
-	// Create column 11 of threshold values = -1.
-	// 9,990 instead of 10,000 because we dropped the 10 labels.
-	thresholds := make([][]float64, len(training_data))
-	for i := range thresholds {
-		thresholds[i] = []float64{-1}
-	}
-
-	// Concatenate the threshold values to the training data.
-	for i := range training_data {
-		training_data[i] = append(training_data[i], thresholds[i]...)
-	}
-
-	// Concatenate the class labels to the training data.
-	for i := range training_data {
-		training_data[i] = append(training_data[i], float64(class_labels[i][0]))
-	}
-
-	rand.Shuffle(len(training_data), func(i, j int) {
-		training_data[i], training_data[j] = training_data[j], training_data[i]
-	})
-
-	if len(training_data) != 9990 {
-		return nil, fmt.Errorf("training data length is %d, expected 9990", len(training_data))
-	}
-	if len(training_data[0]) != 11 {
-		return nil, fmt.Errorf("training data width is %d, expected 11", len(training_data[0]))
-	}
-
-	return training_data, nil
+	return DefaultPipeline().GetTrainingData(verbose)
 }
 
 // GetValidationData returns a slice of slices of floats representing the
@@ -85,66 +27,66 @@ func GetTrainingData(verbose bool) ([][]float64, error) {
 // The second to last column represents the threshold value (-1) for the image.
 // The last column represents the class label for the image.
 func GetValidationData(verbose bool) ([][]float64, error) {
+	return DefaultPipeline().GetValidationData(verbose)
+}
+
+// GetPixelTrainingData returns the training data in the same layout as
+// GetTrainingData, except that the feature values of each image are its 784
+// greyscale pixel values scaled to [0, 1] rather than the extracted features.
+// Pixel values are cheap to extract, so they are not cached.
+func GetPixelTrainingData(verbose bool) ([][]float64, error) {
 	if verbose {
 		fmt.Println("------------------------------------------------")
-		fmt.Println("Building Validation Set...")
+		fmt.Println("Building Pixel Training Set...")
 	}
 
-	validation_data := [][]float64{}
-	class_labels := [][]int{}
-
-	num_files := 10
-	for i := 0; i < num_files; i++ {
-		filename := fmt.Sprintf("input_files/validation_data/handwritten_samples_%d.csv", i)
-		images, labels, err := helpers.ExtractImages(filename, true)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, label := range labels {
-			class_labels = append(class_labels, []int{label})
-		}
-
-		if verbose {
-			fmt.Printf("\tComputing Feature Values in < %s >...\n", filename)
-		}
+	return getDataSet(TrainingDirectory, getPixelValues, "", nil, verbose)
+}
 
-		for _, image := range images {
-			binary_image := helpers.GetBlackWhite(image, 128)
-			feature_values := feature_extraction.GetFeatureValues(binary_image)
-			validation_data = append(validation_data, feature_values)
-		}
+// GetPixelValidationData returns the validation data in the same layout as
+// GetValidationData, except that the feature values of each image are its 784
+// greyscale pixel values scaled to [0, 1] rather than the extracted features.
+func GetPixelValidationData(verbose bool) ([][]float64, error) {
+	if verbose {
+		fmt.Println("------------------------------------------------")
+		fmt.Println("Building Pixel Validation Set...")
 	}
 
-	// Create column 11 of threshold values = -1.
-	// 9,990 instead of 10,000 because we dropped the 10 labels.
-	thresholds := make([][]float64, len(validation_data))
-	for row := range thresholds {
-		thresholds[row] = []float64{-1}
-	}
+	return getDataSet(ValidationDirectory, getPixelValues, "", nil, verbose)
+}
 
-	// Concatenate the threshold values to the training data.
-	for row := range validation_data {
-		validation_data[row] = append(validation_data[row], thresholds[row]...)
+// getPixelValues returns the scaled pixel values of the provided greyscale
+// image.
+func getPixelValues(image helpers.Image) ([]float64, error) {
+	return feature_extraction.GetPixelValues(image), nil
+}
+
+// getDataSet reads the ten handwritten_samples_%d.csv files in the provided
+// directory and returns one shuffled row per image. Each row holds the values
+// returned by extract, followed by the threshold value (-1) and the class label.
+// If augmentation is not nil, augmentation.Multiplier randomly distorted copies
+// of each image are added alongside it, with the same class label.
+//
+// If cache_key is not empty, it must identify extract: the values extracted
+// from the images of each file are then stored in CacheDirectory and reused
+// by later calls with the same key, as long as the file is unchanged.
+// Augmented images are always extracted afresh.
+func getDataSet(directory string, extract func(helpers.Image) ([]float64, error), cache_key string, augmentation *img_manip.Augmentation, verbose bool) ([][]float64, error) {
+	samples, err := getSamples(GetDataSetFiles(directory), true, extract, cache_key, augmentation, verbose)
+	if err != nil {
+		return nil, err
 	}
 
-	// Concatenate the class labels to the training data.
-	for row := range validation_data {
-		validation_data[row] = append(validation_data[row], float64(class_labels[row][0]))
+	data := make([][]float64, len(samples))
+	for i, sample := range samples {
+		data[i] = sample.getRow()
 	}
 
-	rand.Shuffle(len(validation_data), func(i, j int) {
-		validation_data[i], validation_data[j] = validation_data[j], validation_data[i]
+	rand.Shuffle(len(data), func(i, j int) {
+		data[i], data[j] = data[j], data[i]
 	})
 
-	if len(validation_data) != 2490 {
-		return nil, fmt.Errorf("validation data length is %d, expected 2490", len(validation_data))
-	}
-	if len(validation_data[0]) != 11 {
-		return nil, fmt.Errorf("validation data width is %d, expected 11", len(validation_data[0]))
-	}
-
-	return validation_data, nil
+	return data, nil
 }
 
 // GetTestingData returns a slice of slices of floats representing the testing
@@ -154,42 +96,7 @@ func GetValidationData(verbose bool) ([][]float64, error) {
 // The last column represents the threshold value (-1) for the image.
 // The class label for each image is omitted.
 func GetTestingData(file string, verbose bool) ([][]float64, error) {
-	if verbose {
-		fmt.Println("------------------------------------------------")
-		fmt.Println("Building Testing Set...")
-		fmt.Printf("\tComputing Feature Values in < %s >...\n", file)
-	}
-
-	testing_data := [][]float64{}
-
-	images, _, err := helpers.ExtractImages(file, false)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, image := range images {
-		binary_image := helpers.GetBlackWhite(image, 128)
-		feature_values := feature_extraction.GetFeatureValues(binary_image)
-		testing_data = append(testing_data, feature_values)
-	}
-
-	thresholds := make([][]float64, len(testing_data))
-	for row := range thresholds {
-		thresholds[row] = []float64{-1}
-	}
-
-	for row := range testing_data {
-		testing_data[row] = append(testing_data[row], thresholds[row]...)
-	}
-
-	if len(testing_data) != 99 {
-		return nil, fmt.Errorf("testing data length is %d, expected 99", len(testing_data))
-	}
-	if len(testing_data[0]) != 10 {
-		return nil, fmt.Errorf("testing data width is %d, expected 11", len(testing_data[0]))
-	}
-
-	return testing_data, nil
+	return DefaultPipeline().GetTestingData(file, verbose)
 }
 
 // Train will train the model, returning the best weights and the total number
@@ -206,47 +113,107 @@ func Train(weight_vectors [][]float64, epochs int) ([][]float64, int, int, error
 		return nil, 0, 0, err
 	}
 
-	weights_after_each_epoch := [][][]float64{}
-	successes_after_each_epoch := []int{}
-	errors_after_each_epoch := []int{}
+	return TrainOnData(weight_vectors, epochs, training_results, validation_results)
+}
+
+// TrainOnData is Train on the provided training and validation rows rather
+// than the default data set, so that feature values computed elsewhere (see
+// ImportDataSet) can be trained on. The rows are laid out like those returned
+// by GetTrainingData.
+func TrainOnData(weight_vectors [][]float64, epochs int, training_results, validation_results [][]float64) ([][]float64, int, int, error) {
+	weights, err := helpers.NewMatrixFromRows(weight_vectors)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if err := checkRows(weights, training_results, validation_results); err != nil {
+		return nil, 0, 0, err
+	}
+	logits := make([]float64, weights.NumRows)
 
 	learning_rate := 0.08 // eta η
-	for epoch := 0; epoch < epochs; epoch++ {
-		for _, row := range training_results {
-			class_label := int(row[10])
-			features := row[:10]
+	best_weights, total_successes, total_errors := trainEpochs(weights, epochs, training_results, validation_results, nil,
+		func(features []float64, class_label int) {
+			updatePerceptron(weights, features, class_label, learning_rate, logits)
+		})
 
-			logits := make([]float64, len(weight_vectors))
-			for i, weights := range weight_vectors {
-				logits[i] = helpers.DotProduct(weights, features)
-			}
+	return best_weights, total_successes, total_errors, nil
+}
 
-			predicted_label := helpers.ArgMax(logits)
+// trainEpochs makes the provided number of passes over the training rows,
+// calling update with the feature values and class label of each row, which
+// must update the provided weights in place. If random is not nil, the rows
+// are visited in a new random order each pass.
+//
+// Every linear model in this package chooses its epoch the same way: after
+// each pass the weights are validated against held-out validation rows, and
+// the weights of the pass with the fewest errors, the earliest on a tie, are
+// returned. The total number of successful and unsuccessful predictions over
+// all passes is returned as well.
+func trainEpochs(weights helpers.Matrix, epochs int, training_rows, validation_rows [][]float64, random *rand.Rand, update func(features []float64, class_label int)) ([][]float64, int, int) {
+	order := make([]int, len(training_rows))
+	for i := range order {
+		order[i] = i
+	}
 
-			if predicted_label != class_label {
-				adjusted_features := helpers.Multiply(features, learning_rate)
-				weight_vectors[predicted_label] = helpers.SubtractVectors(weight_vectors[predicted_label], adjusted_features)
-				weight_vectors[class_label] = helpers.AddVectors(weight_vectors[class_label], adjusted_features)
-			}
+	best_weights, best_errors := weights.Copy(), -1
+	total_successes, total_errors := 0, 0
+	for epoch := 0; epoch < epochs; epoch++ {
+		if random != nil {
+			random.Shuffle(len(order), func(i, j int) {
+				order[i], order[j] = order[j], order[i]
+			})
 		}
 
-		successes, errors := Validate(weight_vectors, validation_results)
+		for _, i := range order {
+			row := training_rows[i]
+			update(row[:len(row)-1], int(row[len(row)-1]))
+		}
 
-		weights_after_each_epoch = append(weights_after_each_epoch, weight_vectors)
-		successes_after_each_epoch = append(successes_after_each_epoch, successes)
-		errors_after_each_epoch = append(errors_after_each_epoch, errors)
+		successes, errors := Validate(weights.Rows(), validation_rows)
+		if best_errors < 0 || errors < best_errors {
+			best_weights.CopyFrom(weights)
+			best_errors = errors
+		}
+		total_successes += successes
+		total_errors += errors
 	}
 
-	best_epoch := helpers.ArgMin(errors_after_each_epoch)
-	best_weights := weights_after_each_epoch[best_epoch]
+	return best_weights.Rows(), total_successes, total_errors
+}
 
-	total_successes, total_errors := 0, 0
-	for epoch := 0; epoch < epochs; epoch++ {
-		total_successes += successes_after_each_epoch[epoch]
-		total_errors += errors_after_each_epoch[epoch]
+// holdOut shuffles a copy of the provided rows with the provided source of
+// randomness and splits it into training rows and the provided fraction of
+// held-out validation rows, by which Fit chooses its epoch.
+func holdOut(rows [][]float64, fraction float64, random *rand.Rand) ([][]float64, [][]float64, error) {
+	num_held_out := int(fraction * float64(len(rows)))
+	if num_held_out < 1 || num_held_out >= len(rows) {
+		return nil, nil, fmt.Errorf("holdout fraction %f of %d rows leaves no rows to hold out or train on", fraction, len(rows))
 	}
 
-	return best_weights, total_successes, total_errors, nil
+	shuffled := make([][]float64, len(rows))
+	copy(shuffled, rows)
+	random.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	return shuffled[num_held_out:], shuffled[:num_held_out], nil
+}
+
+// checkRows returns an error unless each of the provided rows has one feature
+// value per column of the provided weights, followed by a class label with a
+// row of weights.
+func checkRows(weights helpers.Matrix, row_sets ...[][]float64) error {
+	for _, rows := range row_sets {
+		for i, row := range rows {
+			if len(row) != weights.NumCols+1 {
+				return fmt.Errorf("row %d has %d columns, expected %d", i, len(row), weights.NumCols+1)
+			}
+			if class_label := int(row[len(row)-1]); class_label < 0 || class_label >= weights.NumRows {
+				return fmt.Errorf("row %d has class label %d, expected 0 to %d", i, class_label, weights.NumRows-1)
+			}
+		}
+	}
+	return nil
 }
 
 // Validate will validate the provided weight vectors against the provided
@@ -256,15 +223,10 @@ func Validate(weight_vectors [][]float64, validation_results [][]float64) (int,
 	successes, errors := 0, 0
 
 	for _, row := range validation_results {
-		class_label := int(row[10])
-		features := row[:10]
-
-		logits := make([]float64, len(weight_vectors))
-		for i, weights := range weight_vectors {
-			logits[i] = helpers.DotProduct(weights, features)
-		}
+		class_label := int(row[len(row)-1])
+		features := row[:len(row)-1]
 
-		predicted_label := helpers.ArgMax(logits)
+		predicted_label := helpers.ArgMax(getLogits(weight_vectors, features))
 
 		if predicted_label == class_label {
 			successes++
@@ -279,25 +241,31 @@ func Validate(weight_vectors [][]float64, validation_results [][]float64) (int,
 // GetPredictions will return a slice of ints representing the predicted labels
 // for the provided file using the provided weight vectors.
 func GetPredictions(file string, weight_vectors [][]float64) []int {
-	testing_results, err := GetTestingData(file, false)
+	predictions, err := GetClassifierPredictions(file, &Perceptron{Weights: weight_vectors})
 	if err != nil {
 		fmt.Println(err)
 		return nil
 	}
 
-	predictions := make([]int, len(testing_results))
-
-	for i, row := range testing_results {
-		features := row[:10]
-
-		logits := make([]float64, len(weight_vectors))
-		for i, weights := range weight_vectors {
-			logits[i] = helpers.DotProduct(weights, features)
-		}
+	return predictions
+}
 
-		predicted_label := helpers.ArgMax(logits)
-		predictions[i] = predicted_label
+// updatePerceptron applies the perceptron learning rule for a single row: if
+// the provided weight vectors mislabel the features, the weights of the
+// predicted class are moved away from them and the weights of the true class
+// are moved towards them. It reports whether the row was mislabelled.
+//
+// The weights are updated in place and the logits are computed into the
+// provided buffer, which must have room for one logit per class, so that
+// updatePerceptron does not allocate.
+func updatePerceptron(weights helpers.Matrix, features []float64, class_label int, learning_rate float64, logits []float64) bool {
+	predicted_label := helpers.ArgMax(weights.MulVec(features, logits))
+
+	if predicted_label == class_label {
+		return false
 	}
 
-	return predictions
+	helpers.Axpy(-learning_rate, features, weights.Row(predicted_label))
+	helpers.Axpy(learning_rate, features, weights.Row(class_label))
+	return true
 }
@@ -0,0 +1,170 @@
+package model
+
+import (
+	"fmt"
+	"math"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// GaussianNB is a Gaussian Naive Bayes classifier. It models each feature
+// value as independently normally distributed within each class and labels a
+// new row with the class of highest posterior probability.
+type GaussianNB struct {
+	Priors    []float64   // prior probability of each class
+	Means     [][]float64 // mean of each feature value, per class
+	Variances [][]float64 // variance of each feature value, per class
+}
+
+// NewGaussianNB returns an unfitted Gaussian Naive Bayes classifier.
+func NewGaussianNB() *GaussianNB {
+	return &GaussianNB{}
+}
+
+// Fit estimates the class priors and the per-class mean and variance of each
+// feature value from the provided training rows. The last column of each row
+// is the class label and the remaining columns are the feature values.
+func (nb *GaussianNB) Fit(rows [][]float64) error {
+	counts, means, err := getClassMeans(rows)
+	if err != nil {
+		return err
+	}
+
+	num_classes, num_features := len(means), len(means[0])
+
+	variances := make([][]float64, num_classes)
+	for class := range variances {
+		variances[class] = make([]float64, num_features)
+	}
+	for _, row := range rows {
+		class := int(row[len(row)-1])
+		for i := 0; i < num_features; i++ {
+			difference := row[i] - means[class][i]
+			variances[class][i] += difference * difference
+		}
+	}
+
+	// Smooth the variances so that constant features (such as the threshold
+	// column) do not divide by zero.
+	max_variance := 0.0
+	for class := range variances {
+		for i := range variances[class] {
+			if counts[class] > 0 {
+				variances[class][i] /= float64(counts[class])
+			}
+			max_variance = math.Max(max_variance, variances[class][i])
+		}
+	}
+	smoothing := 1e-9 * math.Max(max_variance, 1)
+	for class := range variances {
+		for i := range variances[class] {
+			variances[class][i] += smoothing
+		}
+	}
+
+	priors := make([]float64, num_classes)
+	for class := range priors {
+		priors[class] = float64(counts[class]) / float64(len(rows))
+	}
+
+	nb.Priors, nb.Means, nb.Variances = priors, means, variances
+	return nil
+}
+
+// Predict returns the predicted label for the provided feature values.
+func (nb *GaussianNB) Predict(features []float64) int {
+	return helpers.ArgMax(nb.getLogPosteriors(features))
+}
+
+// PredictScores returns the posterior probability of each class given the
+// provided feature values.
+func (nb *GaussianNB) PredictScores(features []float64) []float64 {
+	return getSoftmax(nb.getLogPosteriors(features))
+}
+
+// Probabilities reports that the scores of the classifier are posterior
+// probabilities.
+func (nb *GaussianNB) Probabilities() bool {
+	return true
+}
+
+// Save writes the classifier to the provided file as JSON.
+func (nb *GaussianNB) Save(file string) error {
+	return saveJSON(file, nb)
+}
+
+// Load reads a classifier previously written by Save from the provided file.
+func (nb *GaussianNB) Load(file string) error {
+	return loadJSON(file, nb)
+}
+
+// getLogPosteriors returns the unnormalized log posterior probability of each
+// class given the provided feature values.
+func (nb *GaussianNB) getLogPosteriors(features []float64) []float64 {
+	log_posteriors := make([]float64, len(nb.Priors))
+
+	for class := range log_posteriors {
+		if nb.Priors[class] == 0 {
+			log_posteriors[class] = math.Inf(-1)
+			continue
+		}
+
+		log_posterior := math.Log(nb.Priors[class])
+		for i, mean := range nb.Means[class] {
+			variance := nb.Variances[class][i]
+			difference := features[i] - mean
+			log_posterior -= 0.5 * (math.Log(2*math.Pi*variance) + difference*difference/variance)
+		}
+		log_posteriors[class] = log_posterior
+	}
+
+	return log_posteriors
+}
+
+// getClassMeans returns the number of rows in each class and the mean of each
+// feature value per class for the provided training rows. Classes are indexed
+// by label, from 0 to the largest label found.
+func getClassMeans(rows [][]float64) ([]int, [][]float64, error) {
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("no training rows provided")
+	}
+
+	num_features := len(rows[0]) - 1
+	num_classes := 0
+	for _, row := range rows {
+		if len(row)-1 != num_features {
+			return nil, nil, fmt.Errorf("training rows have %d and %d feature values", num_features, len(row)-1)
+		}
+		class := int(row[len(row)-1])
+		if class < 0 {
+			return nil, nil, fmt.Errorf("class label %d is negative", class)
+		}
+		if class+1 > num_classes {
+			num_classes = class + 1
+		}
+	}
+
+	counts := make([]int, num_classes)
+	means := make([][]float64, num_classes)
+	for class := range means {
+		means[class] = make([]float64, num_features)
+	}
+
+	for _, row := range rows {
+		class := int(row[len(row)-1])
+		counts[class]++
+		for i := 0; i < num_features; i++ {
+			means[class][i] += row[i]
+		}
+	}
+
+	for class := range means {
+		for i := range means[class] {
+			if counts[class] > 0 {
+				means[class][i] /= float64(counts[class])
+			}
+		}
+	}
+
+	return counts, means, nil
+}
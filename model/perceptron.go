@@ -0,0 +1,129 @@
+package model
+
+import (
+	"fmt"
+	"math/rand"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// Perceptron is a single-layer multi-class perceptron with one weight vector
+// per class. It is the Classifier form of Train.
+type Perceptron struct {
+	Epochs          int
+	LearningRate    float64 // eta η
+	HoldoutFraction float64 // share of the rows held out to choose the epoch
+	Seed            int64   // seeds the initial weights and the order of the rows
+
+	Weights [][]float64
+}
+
+// NewPerceptron returns an unfitted perceptron that holds out a fifth of its
+// training rows.
+func NewPerceptron(epochs int, learning_rate float64, seed int64) *Perceptron {
+	return &Perceptron{Epochs: epochs, LearningRate: learning_rate, HoldoutFraction: 0.2, Seed: seed}
+}
+
+// Fit trains the perceptron for Epochs passes, visiting the rows in a
+// different random order each pass. If Weights is unset it is first filled
+// with small random weights. HoldoutFraction of the rows are set aside, and
+// the weights from the pass that mislabels the fewest of them are kept, as in
+// Train.
+func (perceptron *Perceptron) Fit(rows [][]float64) error {
+	if len(rows) == 0 {
+		return fmt.Errorf("no training rows provided")
+	}
+
+	random := rand.New(rand.NewSource(perceptron.Seed))
+	num_classes, err := getNumClasses(rows)
+	if err != nil {
+		return err
+	}
+	num_features := len(rows[0]) - 1
+
+	weight_vectors := perceptron.Weights
+	if len(weight_vectors) == 0 {
+		weight_vectors = make([][]float64, num_classes)
+		for i := range weight_vectors {
+			weight_vectors[i] = make([]float64, num_features)
+			for j := range weight_vectors[i] {
+				weight_vectors[i][j] = random.Float64()*0.1 - 0.05
+			}
+		}
+	}
+	if len(weight_vectors) < num_classes {
+		return fmt.Errorf("weights have %d classes, expected at least %d", len(weight_vectors), num_classes)
+	}
+	for i, weights := range weight_vectors {
+		if len(weights) != num_features {
+			return fmt.Errorf("weights of class %d have %d values, expected %d", i, len(weights), num_features)
+		}
+	}
+
+	weights, err := helpers.NewMatrixFromRows(weight_vectors)
+	if err != nil {
+		return err
+	}
+	if err := checkRows(weights, rows); err != nil {
+		return err
+	}
+	logits := make([]float64, weights.NumRows)
+
+	training_rows, validation_rows, err := holdOut(rows, perceptron.HoldoutFraction, random)
+	if err != nil {
+		return err
+	}
+
+	perceptron.Weights, _, _ = trainEpochs(weights, perceptron.Epochs, training_rows, validation_rows, random,
+		func(features []float64, class_label int) {
+			updatePerceptron(weights, features, class_label, perceptron.LearningRate, logits)
+		})
+	return nil
+}
+
+// Predict returns the predicted label for the provided feature values.
+func (perceptron *Perceptron) Predict(features []float64) int {
+	return helpers.ArgMax(perceptron.PredictScores(features))
+}
+
+// PredictScores returns the logit of each class for the provided feature
+// values.
+func (perceptron *Perceptron) PredictScores(features []float64) []float64 {
+	return getLogits(perceptron.Weights, features)
+}
+
+// Save writes the perceptron to the provided file as JSON.
+func (perceptron *Perceptron) Save(file string) error {
+	return saveJSON(file, perceptron)
+}
+
+// Load reads a perceptron previously written by Save from the provided file.
+func (perceptron *Perceptron) Load(file string) error {
+	return loadJSON(file, perceptron)
+}
+
+// getLogits returns the dot product of each of the provided weight vectors
+// with the provided feature values.
+func getLogits(weight_vectors [][]float64, features []float64) []float64 {
+	logits := make([]float64, len(weight_vectors))
+	for i, weights := range weight_vectors {
+		logits[i] = helpers.DotProduct(weights, features)
+	}
+	return logits
+}
+
+// getNumClasses returns one more than the largest class label in the provided
+// rows, or an error if any class label is negative.
+func getNumClasses(rows [][]float64) (int, error) {
+	num_classes := 0
+	for i, row := range rows {
+		class := int(row[len(row)-1])
+		if class < 0 {
+			return 0, fmt.Errorf("row %d has negative class label %d", i, class)
+		}
+		if class+1 > num_classes {
+			num_classes = class + 1
+		}
+	}
+	return num_classes, nil
+}
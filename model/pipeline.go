@@ -0,0 +1,178 @@
+package model
+
+import (
+	"fmt"
+
+	"project04_perceptron/go_rewrite/feature_extraction"
+	"project04_perceptron/go_rewrite/helpers"
+	"project04_perceptron/go_rewrite/img_manip"
+)
+
+// Pipeline describes how each greyscale image of a data set is turned into a
+// row of feature values: the image is normalized by Normalization, if set,
+// then converted to black and white by Binarization, cleaned up by the
+// morphological operations in Morphology and, if Thinning is set, reduced to
+// its skeleton; then each of the feature extractors registered in
+// feature_extraction under the names in Features is applied in turn.
+//
+// If Augmentation is set, the training data also holds randomly distorted
+// copies of each training image, which go through the same steps. The
+// validation and testing data are never augmented.
+type Pipeline struct {
+	Normalization *img_manip.Normalization `json:",omitempty"`
+	Binarization  helpers.Binarization
+	Morphology    []img_manip.MorphologyStep `json:",omitempty"`
+	Thinning      img_manip.ThinningMethod   `json:",omitempty"`
+	Features      []string
+
+	Augmentation *img_manip.Augmentation `json:",omitempty"`
+}
+
+// DefaultPipeline returns the pipeline used by GetTrainingData,
+// GetValidationData and GetTestingData: a fixed threshold of 128 and the nine
+// features of feature_extraction.GetFeatureValues.
+func DefaultPipeline() Pipeline {
+	return Pipeline{
+		Binarization: helpers.Binarization{Method: helpers.Fixed, Threshold: 128},
+		Features:     []string{"basic"},
+	}
+}
+
+// GetFeatureNames returns the name of each feature value produced by the
+// pipeline, in order.
+func (pipeline Pipeline) GetFeatureNames() ([]string, error) {
+	return feature_extraction.GetFeatureSetNames(pipeline.Features)
+}
+
+// GetFeatureValues returns the feature values of the provided greyscale image.
+func (pipeline Pipeline) GetFeatureValues(image helpers.Image) ([]float64, error) {
+	if pipeline.Normalization != nil {
+		normalized, err := img_manip.Normalize(image, *pipeline.Normalization)
+		if err != nil {
+			return nil, err
+		}
+		image = normalized
+	}
+
+	binary_image, err := helpers.Binarize(image, pipeline.Binarization)
+	if err != nil {
+		return nil, err
+	}
+
+	binary_image, err = img_manip.ApplyMorphology(binary_image, pipeline.Morphology)
+	if err != nil {
+		return nil, err
+	}
+
+	if pipeline.Thinning != "" {
+		skeleton, err := img_manip.Thin(binary_image, pipeline.Thinning)
+		if err != nil {
+			return nil, err
+		}
+		binary_image = skeleton
+	}
+
+	return feature_extraction.GetFeatureSetValues(image, binary_image, pipeline.Features)
+}
+
+// GetTrainingData returns the training data in the layout described by
+// GetTrainingData, with the feature values produced by the pipeline.
+func (pipeline Pipeline) GetTrainingData(verbose bool) ([][]float64, error) {
+	if verbose {
+		fmt.Println("------------------------------------------------")
+		fmt.Println("Building Training Set...")
+	}
+
+	cache_key, err := pipeline.getCacheKey()
+	if err != nil {
+		return nil, err
+	}
+
+	training_data, err := getDataSet(TrainingDirectory, pipeline.GetFeatureValues, cache_key, pipeline.Augmentation, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	expected_length := 9990
+	if pipeline.Augmentation != nil {
+		expected_length *= 1 + pipeline.Augmentation.Multiplier
+	}
+	if err := pipeline.checkShape(training_data, expected_length, 2); err != nil {
+		return nil, fmt.Errorf("training data %v", err)
+	}
+
+	return training_data, nil
+}
+
+// GetValidationData returns the validation data in the layout described by
+// GetValidationData, with the feature values produced by the pipeline.
+func (pipeline Pipeline) GetValidationData(verbose bool) ([][]float64, error) {
+	if verbose {
+		fmt.Println("------------------------------------------------")
+		fmt.Println("Building Validation Set...")
+	}
+
+	cache_key, err := pipeline.getCacheKey()
+	if err != nil {
+		return nil, err
+	}
+
+	validation_data, err := getDataSet(ValidationDirectory, pipeline.GetFeatureValues, cache_key, nil, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pipeline.checkShape(validation_data, 2490, 2); err != nil {
+		return nil, fmt.Errorf("validation data %v", err)
+	}
+
+	return validation_data, nil
+}
+
+// GetTestingData returns the testing data in the provided file in the layout
+// described by GetTestingData, with the feature values produced by the
+// pipeline.
+func (pipeline Pipeline) GetTestingData(file string, verbose bool) ([][]float64, error) {
+	if verbose {
+		fmt.Println("------------------------------------------------")
+		fmt.Println("Building Testing Set...")
+		fmt.Printf("\tComputing Feature Values in < %s >...\n", file)
+	}
+
+	samples, err := getSamples([]string{file}, false, pipeline.GetFeatureValues, "", nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	testing_data := make([][]float64, len(samples))
+	for i, sample := range samples {
+		// Concatenate the threshold value (-1) to the feature values.
+		testing_data[i] = append(sample.Features, -1)
+	}
+
+	if err := pipeline.checkShape(testing_data, 99, 1); err != nil {
+		return nil, fmt.Errorf("testing data %v", err)
+	}
+
+	return testing_data, nil
+}
+
+// checkShape returns an error unless the provided data has the expected
+// number of rows and each row has one column per feature plus the provided
+// number of extra columns.
+func (pipeline Pipeline) checkShape(data [][]float64, expected_length, extra_columns int) error {
+	feature_names, err := pipeline.GetFeatureNames()
+	if err != nil {
+		return err
+	}
+	expected_width := len(feature_names) + extra_columns
+
+	if len(data) != expected_length {
+		return fmt.Errorf("length is %d, expected %d", len(data), expected_length)
+	}
+	if len(data[0]) != expected_width {
+		return fmt.Errorf("width is %d, expected %d", len(data[0]), expected_width)
+	}
+
+	return nil
+}
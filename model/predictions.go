@@ -0,0 +1,293 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Submission is a prediction format holding only an ImageId column, which is
+// the row index counting from 1, and a Label column, as expected by Kaggle's
+// digit recognizer competition.
+const Submission ExportFormat = "submission"
+
+// ProbabilityClassifier is implemented by a Classifier that declares whether
+// its scores are probabilities, such as votes or posteriors, rather than
+// logits or margins. A Classifier that does not implement it is taken to
+// return scores that are not probabilities.
+type ProbabilityClassifier interface {
+	Classifier
+
+	// Probabilities reports whether the scores returned by PredictScores are
+	// probabilities that sum to 1.
+	Probabilities() bool
+}
+
+var (
+	_ ProbabilityClassifier = (*KNN)(nil)
+	_ ProbabilityClassifier = (*GaussianNB)(nil)
+	_ ProbabilityClassifier = (*DecisionTree)(nil)
+	_ ProbabilityClassifier = (*RandomForest)(nil)
+	_ ProbabilityClassifier = (*Ensemble)(nil)
+)
+
+// ClassScore is the score of one class for an image.
+type ClassScore struct {
+	Label      int
+	Score      float64 // as returned by the classifier's PredictScores
+	Confidence float64 // estimated probability of the class, from 0 to 1
+}
+
+// Prediction is a classifier's prediction for one row of an image file. Source
+// and Row identify the row, so predictions can be joined back to their input.
+type Prediction struct {
+	Source string
+	Row    int // index of the row among the rows of data of Source, from 0
+	ClassScore
+
+	// TopK holds the highest scoring classes, best first. Its first entry is
+	// the predicted class.
+	TopK []ClassScore
+}
+
+// GetScoredPredictions returns the classifier's prediction for each image in
+// the provided unlabelled file, using the feature values produced by the
+// pipeline, along with the top_k highest scoring classes of each image.
+func (pipeline Pipeline) GetScoredPredictions(file string, classifier Classifier, top_k int, verbose bool) ([]Prediction, error) {
+	if top_k < 1 {
+		return nil, fmt.Errorf("top k must be at least 1, got %d", top_k)
+	}
+
+	if verbose {
+		fmt.Println("------------------------------------------------")
+		fmt.Println("Predicting Labels...")
+	}
+
+	samples, err := getSamples([]string{file}, false, pipeline.GetFeatureValues, "", nil, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	probabilities := false
+	if probability_classifier, ok := classifier.(ProbabilityClassifier); ok {
+		probabilities = probability_classifier.Probabilities()
+	}
+
+	predictions := make([]Prediction, len(samples))
+	for i, sample := range samples {
+		// Concatenate the threshold value (-1) to the feature values.
+		features := append(sample.Features, -1)
+		class_scores := getClassScores(classifier.PredictScores(features), probabilities)
+		if len(class_scores) == 0 {
+			return nil, fmt.Errorf("classifier returned no scores for row %d of %s", sample.Row, sample.Source)
+		}
+
+		predictions[i] = Prediction{
+			Source:     sample.Source,
+			Row:        sample.Row,
+			ClassScore: class_scores[0],
+			TopK:       class_scores[:min(top_k, len(class_scores))],
+		}
+	}
+
+	return predictions, nil
+}
+
+// getClassScores returns the score and confidence of each class, best first.
+// Scores that are probabilities are their own confidence; any other scores are
+// turned into confidences by getConfidences.
+func getClassScores(scores []float64, probabilities bool) []ClassScore {
+	confidences := scores
+	if !probabilities {
+		confidences = getConfidences(scores)
+	}
+
+	class_scores := make([]ClassScore, len(scores))
+	for i := range scores {
+		class_scores[i] = ClassScore{Label: i, Score: scores[i], Confidence: confidences[i]}
+	}
+
+	// Ties go to the lower label, like Predict. NaN ranks below every other
+	// score, so that the order is well defined.
+	rank := func(score float64) float64 {
+		if math.IsNaN(score) {
+			return math.Inf(-1)
+		}
+		return score
+	}
+	sort.SliceStable(class_scores, func(i, j int) bool {
+		return rank(class_scores[i].Score) > rank(class_scores[j].Score)
+	})
+	return class_scores
+}
+
+// getConfidences returns the softmax of the provided scores, leaving out any
+// score that is not finite, which would otherwise turn every confidence into
+// NaN. Such scores have a confidence of 0, except +Inf: if any score is +Inf,
+// the confidence is shared equally between the +Inf scores.
+func getConfidences(scores []float64) []float64 {
+	confidences := make([]float64, len(scores))
+
+	var finite, infinite []int
+	for i, score := range scores {
+		switch {
+		case math.IsInf(score, 1):
+			infinite = append(infinite, i)
+		case !math.IsNaN(score) && !math.IsInf(score, -1):
+			finite = append(finite, i)
+		}
+	}
+
+	if len(infinite) > 0 {
+		for _, i := range infinite {
+			confidences[i] = 1 / float64(len(infinite))
+		}
+		return confidences
+	}
+	if len(finite) == 0 {
+		return confidences
+	}
+
+	finite_scores := make([]float64, len(finite))
+	for k, i := range finite {
+		finite_scores[k] = scores[i]
+	}
+	for k, confidence := range getSoftmax(finite_scores) {
+		confidences[finite[k]] = confidence
+	}
+	return confidences
+}
+
+// WritePredictions writes the provided predictions to the provided file in
+// the provided format:
+//
+//   - CSV: a header row, then the source, row, label, score and confidence of
+//     each prediction, followed by the label and confidence of each of its
+//     top k classes in columns top1_label, top1_confidence and so on.
+//   - JSONLines: one object per prediction with the same values, its top k
+//     classes as an array of objects under "top_k". A score that is not finite
+//     is written as null.
+//   - Submission: see Submission.
+func WritePredictions(predictions []Prediction, file string, format ExportFormat) error {
+	output := bytes.Buffer{}
+	var err error
+	switch format {
+	case CSV:
+		err = writePredictionsCSV(&output, predictions)
+	case JSONLines:
+		err = writePredictionsJSONLines(&output, predictions)
+	case Submission:
+		err = writeSubmission(&output, predictions)
+	default:
+		err = fmt.Errorf("predictions cannot be written as %q", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, output.Bytes(), 0644)
+}
+
+// writePredictionsCSV writes the provided predictions as CSV.
+func writePredictionsCSV(output io.Writer, predictions []Prediction) error {
+	top_k := 0
+	for _, prediction := range predictions {
+		top_k = max(top_k, len(prediction.TopK))
+	}
+
+	header := []string{sourceColumn, rowColumn, labelColumn, "score", "confidence"}
+	for k := 1; k <= top_k; k++ {
+		header = append(header, fmt.Sprintf("top%d_label", k), fmt.Sprintf("top%d_confidence", k))
+	}
+
+	writer := csv.NewWriter(output)
+	writer.Write(header)
+	for _, prediction := range predictions {
+		record := []string{
+			prediction.Source,
+			strconv.Itoa(prediction.Row),
+			strconv.Itoa(prediction.Label),
+			strconv.FormatFloat(prediction.Score, 'g', -1, 64),
+			strconv.FormatFloat(prediction.Confidence, 'g', -1, 64),
+		}
+		for k := 0; k < top_k; k++ {
+			if k >= len(prediction.TopK) {
+				record = append(record, "", "")
+				continue
+			}
+			record = append(record,
+				strconv.Itoa(prediction.TopK[k].Label),
+				strconv.FormatFloat(prediction.TopK[k].Confidence, 'g', -1, 64))
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// classScoreRecord is a ClassScore as written to JSON Lines.
+type classScoreRecord struct {
+	Label      int      `json:"label"`
+	Score      *float64 `json:"score"`
+	Confidence float64  `json:"confidence"`
+}
+
+// predictionRecord is a Prediction as written to JSON Lines.
+type predictionRecord struct {
+	Source string `json:"source"`
+	Row    int    `json:"row"`
+	classScoreRecord
+	TopK []classScoreRecord `json:"top_k"`
+}
+
+// newClassScoreRecord returns the provided class score as written to JSON
+// Lines, with a score that is not finite, which JSON cannot represent, as
+// null.
+func newClassScoreRecord(class_score ClassScore) classScoreRecord {
+	record := classScoreRecord{Label: class_score.Label, Confidence: class_score.Confidence}
+	if !math.IsNaN(class_score.Score) && !math.IsInf(class_score.Score, 0) {
+		score := class_score.Score
+		record.Score = &score
+	}
+	return record
+}
+
+// writePredictionsJSONLines writes the provided predictions as one JSON object
+// per line.
+func writePredictionsJSONLines(output io.Writer, predictions []Prediction) error {
+	writer := bufio.NewWriter(output)
+	encoder := json.NewEncoder(writer)
+	for _, prediction := range predictions {
+		record := predictionRecord{
+			Source:           prediction.Source,
+			Row:              prediction.Row,
+			classScoreRecord: newClassScoreRecord(prediction.ClassScore),
+			TopK:             make([]classScoreRecord, len(prediction.TopK)),
+		}
+		for k, class_score := range prediction.TopK {
+			record.TopK[k] = newClassScoreRecord(class_score)
+		}
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// writeSubmission writes the provided predictions as a Submission.
+func writeSubmission(output io.Writer, predictions []Prediction) error {
+	writer := csv.NewWriter(output)
+	writer.Write([]string{"ImageId", "Label"})
+	for _, prediction := range predictions {
+		writer.Write([]string{strconv.Itoa(prediction.Row + 1), strconv.Itoa(prediction.Label)})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,175 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// LinearSVM is a multi-class linear support vector machine trained with the
+// Pegasos algorithm. It is the Classifier form of TrainSVM.
+type LinearSVM struct {
+	Epochs          int
+	Lambda          float64 // strength of the L2 penalty λ
+	HoldoutFraction float64 // share of the rows held out to choose the epoch
+	Seed            int64   // seeds the order of the rows
+
+	Weights [][]float64
+}
+
+// NewLinearSVM returns an unfitted linear support vector machine that holds
+// out a fifth of its training rows.
+func NewLinearSVM(epochs int, lambda float64, seed int64) *LinearSVM {
+	return &LinearSVM{Epochs: epochs, Lambda: lambda, HoldoutFraction: 0.2, Seed: seed}
+}
+
+// Fit trains the support vector machine for Epochs passes, visiting the rows
+// in a different random order each pass. HoldoutFraction of the rows are set
+// aside, and the weights from the pass that mislabels the fewest of them are
+// kept, as in TrainSVM. The rows must hold at least two classes.
+func (svm *LinearSVM) Fit(rows [][]float64) error {
+	if len(rows) == 0 {
+		return fmt.Errorf("no training rows provided")
+	}
+	if svm.Lambda <= 0 {
+		return fmt.Errorf("lambda must be positive, got %f", svm.Lambda)
+	}
+
+	num_classes, err := getNumClasses(rows)
+	if err != nil {
+		return err
+	}
+	if num_classes < 2 {
+		return fmt.Errorf("need at least 2 classes to train a support vector machine, got %d", num_classes)
+	}
+
+	weights := helpers.NewMatrix(num_classes, len(rows[0])-1)
+	if err := checkRows(weights, rows); err != nil {
+		return err
+	}
+	logits := make([]float64, num_classes)
+
+	random := rand.New(rand.NewSource(svm.Seed))
+	training_rows, validation_rows, err := holdOut(rows, svm.HoldoutFraction, random)
+	if err != nil {
+		return err
+	}
+
+	step := 0
+	svm.Weights, _, _ = trainEpochs(weights, svm.Epochs, training_rows, validation_rows, random,
+		func(features []float64, class_label int) {
+			step++
+			updateSVM(weights, features, class_label, svm.Lambda, step, logits)
+		})
+	return nil
+}
+
+// Predict returns the predicted label for the provided feature values.
+func (svm *LinearSVM) Predict(features []float64) int {
+	return helpers.ArgMax(svm.PredictScores(features))
+}
+
+// PredictScores returns the margin of each class for the provided feature
+// values.
+func (svm *LinearSVM) PredictScores(features []float64) []float64 {
+	return getLogits(svm.Weights, features)
+}
+
+// Save writes the support vector machine to the provided file as JSON.
+func (svm *LinearSVM) Save(file string) error {
+	return saveJSON(file, svm)
+}
+
+// Load reads a support vector machine previously written by Save from the
+// provided file.
+func (svm *LinearSVM) Load(file string) error {
+	return loadJSON(file, svm)
+}
+
+// TrainSVM will train a multi-class linear support vector machine with
+// num_classes classes and num_features feature values per row on the same
+// training data as Train, returning the best weights and the total number of
+// successful and unsuccessful predictions found during training.
+//
+// The weights are learned with the Pegasos algorithm: stochastic gradient
+// descent on the multi-class (Crammer-Singer) hinge loss with an L2 penalty of
+// strength lambda. The step size at update t is 1 / (lambda * t), so no
+// learning rate needs to be tuned, and the weights start at zero. The returned
+// weights have the same shape as those returned by Train and can be passed
+// directly to Validate and GetPredictions.
+func TrainSVM(num_classes, num_features, epochs int, lambda float64) ([][]float64, int, int, error) {
+	if lambda <= 0 {
+		return nil, 0, 0, fmt.Errorf("lambda must be positive, got %f", lambda)
+	}
+	if num_classes < 2 {
+		return nil, 0, 0, fmt.Errorf("need at least 2 classes to train a support vector machine, got %d", num_classes)
+	}
+
+	verbose := true
+	training_results, err := GetTrainingData(verbose)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	validation_results, err := GetValidationData(verbose)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	weights := helpers.NewMatrix(num_classes, num_features)
+	if err := checkRows(weights, training_results, validation_results); err != nil {
+		return nil, 0, 0, err
+	}
+	logits := make([]float64, num_classes)
+
+	step := 0
+	best_weights, total_successes, total_errors := trainEpochs(weights, epochs, training_results, validation_results, nil,
+		func(features []float64, class_label int) {
+			step++
+			updateSVM(weights, features, class_label, lambda, step, logits)
+		})
+
+	return best_weights, total_successes, total_errors, nil
+}
+
+// updateSVM applies the Pegasos update for a single row at the provided step
+// (counting from 1): every weight vector is shrunk towards zero, and if the
+// true class does not beat the highest scoring incorrect class by a margin of
+// at least 1 the two are moved towards and away from the features
+// respectively. The weights are then projected back onto the ball of radius
+// 1/sqrt(λ), which is known to contain the optimum.
+//
+// Like updatePerceptron, it updates the weights in place and computes the
+// logits into the provided buffer, so it does not allocate.
+func updateSVM(weights helpers.Matrix, features []float64, class_label int, lambda float64, step int, logits []float64) {
+	learning_rate := 1 / (lambda * float64(step)) // eta η
+
+	logits = weights.MulVec(features, logits)
+
+	// The most violating class is the highest scoring incorrect one.
+	rival_label := -1
+	for i := range logits {
+		if i == class_label {
+			continue
+		}
+		if rival_label == -1 || logits[i] > logits[rival_label] {
+			rival_label = i
+		}
+	}
+
+	// Gradient of the L2 penalty: shrink every weight vector.
+	helpers.ScaleInPlace(weights.Data, 1-learning_rate*lambda)
+
+	// Gradient of the hinge loss: only when the margin is violated.
+	if logits[class_label]-logits[rival_label] < 1 {
+		helpers.Axpy(-learning_rate, features, weights.Row(rival_label))
+		helpers.Axpy(learning_rate, features, weights.Row(class_label))
+	}
+
+	norm := math.Sqrt(helpers.DotProduct(weights.Data, weights.Data))
+	if radius := 1 / math.Sqrt(lambda); norm > radius {
+		helpers.ScaleInPlace(weights.Data, radius/norm)
+	}
+}
@@ -0,0 +1,254 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// Criterion names the impurity measure used to choose decision tree splits.
+type Criterion string
+
+const (
+	Gini    Criterion = "gini"
+	Entropy Criterion = "entropy"
+)
+
+// Impurity returns the impurity of a node holding the provided number of rows
+// of each class.
+func (criterion Criterion) Impurity(counts []float64) float64 {
+	total := 0.0
+	for _, count := range counts {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+
+	impurity := 0.0
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := count / total
+		if criterion == Entropy {
+			impurity -= p * math.Log2(p)
+		} else {
+			impurity += p * (1 - p)
+		}
+	}
+	return impurity
+}
+
+// TreeNode is a node of a DecisionTree. Internal nodes send rows whose value
+// of Feature is at most Threshold to Left and all other rows to Right. Leaves
+// have no children and hold the fraction of their training rows in each class.
+type TreeNode struct {
+	Feature      int
+	Threshold    float64
+	Left         *TreeNode `json:",omitempty"`
+	Right        *TreeNode `json:",omitempty"`
+	Distribution []float64 `json:",omitempty"`
+}
+
+// DecisionTree is a CART classification tree grown by repeatedly choosing the
+// axis-aligned split that most reduces Criterion.
+type DecisionTree struct {
+	Criterion       Criterion
+	MaxDepth        int   // maximum depth of the tree, or 0 for no limit
+	MinSamplesSplit int   // minimum number of rows a node needs to be split
+	MinSamplesLeaf  int   // minimum number of rows on each side of a split
+	MaxFeatures     int   // features considered at each split, or 0 for all
+	Seed            int64 // seeds the choice of features when MaxFeatures > 0
+
+	Root        *TreeNode
+	Importances []float64 // impurity decrease due to each feature, summing to 1
+
+	num_classes int
+	random      *rand.Rand
+}
+
+// NewDecisionTree returns an unfitted decision tree.
+func NewDecisionTree(criterion Criterion, max_depth, min_samples_split, min_samples_leaf int) *DecisionTree {
+	return &DecisionTree{
+		Criterion:       criterion,
+		MaxDepth:        max_depth,
+		MinSamplesSplit: min_samples_split,
+		MinSamplesLeaf:  min_samples_leaf,
+	}
+}
+
+// Fit grows the tree from the provided training rows. The last column of each
+// row is the class label and the remaining columns are the feature values.
+func (tree *DecisionTree) Fit(rows [][]float64) error {
+	if len(rows) == 0 {
+		return fmt.Errorf("no training rows provided")
+	}
+
+	tree.num_classes = 0
+	for _, row := range rows {
+		class := int(row[len(row)-1])
+		if class < 0 {
+			return fmt.Errorf("class label %d is negative", class)
+		}
+		if class+1 > tree.num_classes {
+			tree.num_classes = class + 1
+		}
+	}
+
+	tree.random = rand.New(rand.NewSource(tree.Seed))
+	tree.Importances = make([]float64, len(rows[0])-1)
+	tree.Root = tree.grow(rows, 0)
+
+	total := 0.0
+	for _, importance := range tree.Importances {
+		total += importance
+	}
+	if total > 0 {
+		for i := range tree.Importances {
+			tree.Importances[i] /= total
+		}
+	}
+
+	return nil
+}
+
+// Predict returns the predicted label for the provided feature values, or -1
+// if the tree is unfitted.
+func (tree *DecisionTree) Predict(features []float64) int {
+	return helpers.ArgMax(tree.PredictScores(features))
+}
+
+// PredictScores returns the class distribution of the leaf that the provided
+// feature values fall into, or no scores if the tree is unfitted.
+func (tree *DecisionTree) PredictScores(features []float64) []float64 {
+	if tree.Root == nil {
+		return nil
+	}
+
+	node := tree.Root
+	for node.Left != nil {
+		if features[node.Feature] <= node.Threshold {
+			node = node.Left
+		} else {
+			node = node.Right
+		}
+	}
+	return node.Distribution
+}
+
+// Probabilities reports that the scores of the tree are class distributions,
+// which are probabilities.
+func (tree *DecisionTree) Probabilities() bool {
+	return true
+}
+
+// Save writes the tree to the provided file as JSON.
+func (tree *DecisionTree) Save(file string) error {
+	return saveJSON(file, tree)
+}
+
+// Load reads a tree previously written by Save from the provided file.
+func (tree *DecisionTree) Load(file string) error {
+	return loadJSON(file, tree)
+}
+
+// grow returns the subtree built from the provided rows at the provided depth.
+func (tree *DecisionTree) grow(rows [][]float64, depth int) *TreeNode {
+	counts := tree.getClassCounts(rows)
+	impurity := tree.Criterion.Impurity(counts)
+
+	can_split := impurity > 0 &&
+		(tree.MaxDepth <= 0 || depth < tree.MaxDepth) &&
+		len(rows) >= tree.MinSamplesSplit &&
+		len(rows) >= 2*max(tree.MinSamplesLeaf, 1)
+
+	if can_split {
+		feature, threshold, gain, found := tree.findSplit(rows, counts, impurity)
+		if found {
+			left, right := [][]float64{}, [][]float64{}
+			for _, row := range rows {
+				if row[feature] <= threshold {
+					left = append(left, row)
+				} else {
+					right = append(right, row)
+				}
+			}
+
+			tree.Importances[feature] += gain * float64(len(rows))
+
+			return &TreeNode{
+				Feature:   feature,
+				Threshold: threshold,
+				Left:      tree.grow(left, depth+1),
+				Right:     tree.grow(right, depth+1),
+			}
+		}
+	}
+
+	distribution := make([]float64, len(counts))
+	for class := range counts {
+		distribution[class] = counts[class] / float64(len(rows))
+	}
+	return &TreeNode{Distribution: distribution}
+}
+
+// findSplit returns the feature and threshold that most reduce the impurity of
+// the node holding the provided rows, along with that reduction. found is
+// false if no split leaves MinSamplesLeaf rows on each side.
+func (tree *DecisionTree) findSplit(rows [][]float64, counts []float64, impurity float64) (feature int, threshold, gain float64, found bool) {
+	num_features := len(rows[0]) - 1
+	candidates := tree.random.Perm(num_features)
+	if 0 < tree.MaxFeatures && tree.MaxFeatures < num_features {
+		candidates = candidates[:tree.MaxFeatures]
+	}
+
+	min_leaf := max(tree.MinSamplesLeaf, 1)
+	total := float64(len(rows))
+
+	sorted := make([][]float64, len(rows))
+	copy(sorted, rows)
+
+	for _, f := range candidates {
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i][f] < sorted[j][f] })
+
+		left_counts := make([]float64, len(counts))
+		right_counts := append([]float64(nil), counts...)
+
+		for i := 0; i < len(sorted)-1; i++ {
+			class := int(sorted[i][len(sorted[i])-1])
+			left_counts[class]++
+			right_counts[class]--
+
+			num_left := i + 1
+			if num_left < min_leaf || len(sorted)-num_left < min_leaf {
+				continue
+			}
+			if sorted[i][f] == sorted[i+1][f] {
+				continue
+			}
+
+			split_impurity := (float64(num_left)*tree.Criterion.Impurity(left_counts) +
+				float64(len(sorted)-num_left)*tree.Criterion.Impurity(right_counts)) / total
+
+			if impurity-split_impurity > gain {
+				feature, threshold = f, (sorted[i][f]+sorted[i+1][f])/2
+				gain, found = impurity-split_impurity, true
+			}
+		}
+	}
+
+	return feature, threshold, gain, found
+}
+
+// getClassCounts returns the number of the provided rows in each class.
+func (tree *DecisionTree) getClassCounts(rows [][]float64) []float64 {
+	counts := make([]float64, tree.num_classes)
+	for _, row := range rows {
+		counts[int(row[len(row)-1])]++
+	}
+	return counts
+}
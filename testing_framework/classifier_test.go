@@ -0,0 +1,241 @@
+package testing_framework
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"project04_perceptron/go_rewrite/helpers"
+	"project04_perceptron/go_rewrite/model"
+)
+
+// getClusteredRows returns rows of two well separated clusters of feature
+// values, labelled 0 and 1, laid out like the rows of model.GetTrainingData.
+func getClusteredRows(num_rows int, seed int64) [][]float64 {
+	random := rand.New(rand.NewSource(seed))
+
+	rows := make([][]float64, num_rows)
+	for i := range rows {
+		label := i % 2
+		center := float64(4*label - 2)
+		rows[i] = []float64{center + random.NormFloat64()*0.5, center + random.NormFloat64()*0.5, -1, float64(label)}
+	}
+	return rows
+}
+
+// getClassifiers returns a new, unfitted instance of every classifier in the
+// model package, keyed by name.
+func getClassifiers() map[string]func() model.Classifier {
+	return map[string]func() model.Classifier{
+		"perceptron":    func() model.Classifier { return model.NewPerceptron(10, 0.1, 1) },
+		"linear svm":    func() model.Classifier { return model.NewLinearSVM(10, 0.01, 1) },
+		"knn":           func() model.Classifier { return model.NewKNN(3, model.Euclidean, true) },
+		"naive bayes":   func() model.Classifier { return model.NewGaussianNB() },
+		"centroid":      func() model.Classifier { return model.NewNearestCentroid(model.Manhattan) },
+		"decision tree": func() model.Classifier { return model.NewDecisionTree(model.Entropy, 5, 2, 1) },
+		"ensemble":      func() model.Classifier { return model.NewEnsemble(5, 10, model.Stacking, 1) },
+		"random forest": func() model.Classifier { return model.NewRandomForest(10, model.Gini, 1) },
+	}
+}
+
+// TestClassifiers tests that every classifier separates two clusters and
+// makes the same predictions after being saved and loaded.
+func TestClassifiers(t *testing.T) {
+	training_rows, validation_rows := getClusteredRows(200, 1), getClusteredRows(100, 2)
+
+	for name, new_classifier := range getClassifiers() {
+		classifier := new_classifier()
+		if err := classifier.Fit(training_rows); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		successes, errors := model.Evaluate(classifier, validation_rows)
+		if errors > successes/20 {
+			t.Errorf("%s: %d successes and %d errors on separable clusters", name, successes, errors)
+		}
+
+		file := filepath.Join(t.TempDir(), fmt.Sprintf("%s.json", name))
+		if err := classifier.Save(file); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		loaded := new_classifier()
+		if err := loaded.Load(file); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		for i, row := range validation_rows {
+			features := row[:len(row)-1]
+			if classifier.Predict(features) != loaded.Predict(features) {
+				t.Errorf("%s: row %d predicted differently after loading", name, i)
+				break
+			}
+		}
+	}
+}
+
+// TestCrossValidate tests that cross-validation evaluates every row once.
+func TestCrossValidate(t *testing.T) {
+	rows := getClusteredRows(100, 3)
+
+	successes, errors, err := model.CrossValidate(getClassifiers()["naive bayes"], rows, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if successes+errors != len(rows) {
+		t.Errorf("expected %d predictions, got %d", len(rows), successes+errors)
+	}
+}
+
+// TestInvalidClassifiers tests that unfitted or misconfigured classifiers
+// report an error, or predict -1, rather than panicking.
+func TestInvalidClassifiers(t *testing.T) {
+	rows := getClusteredRows(20, 5)
+
+	if _, err := model.NewKNN(3, model.Euclidean, false).PredictAll(rows); err == nil {
+		t.Errorf("expected an error predicting with an unfitted knn")
+	}
+	if scores := model.NewKNN(3, model.Euclidean, false).PredictScores(rows[0][:3]); scores != nil {
+		t.Errorf("expected no scores from an unfitted knn, got %v", scores)
+	}
+	knn := model.NewKNN(1, model.Euclidean, false)
+	if err := knn.Fit([][]float64{{0, -1, 12}, {1, -1, 3}}); err != nil {
+		t.Fatal(err)
+	}
+	if scores := knn.PredictScores([]float64{0, -1}); len(scores) != 13 || scores[12] != 1 {
+		t.Errorf("expected a vote for class 12 out of 13 classes, got %v", scores)
+	}
+	if label := knn.Predict([]float64{0}); label != -1 {
+		t.Errorf("expected a knn to predict -1 for a short row, got %d", label)
+	}
+	if _, err := knn.PredictAll([][]float64{{0}}); err == nil {
+		t.Errorf("expected an error predicting a short row with a knn")
+	}
+	if err := model.NewKNN(3, "chebyshev", false).Fit(rows); err == nil {
+		t.Errorf("expected an error fitting a knn with an unknown metric")
+	}
+	if err := model.NewNearestCentroid("chebyshev").Fit(rows); err == nil {
+		t.Errorf("expected an error fitting a nearest centroid with an unknown metric")
+	}
+	perceptron := model.NewPerceptron(1, 0.1, 1)
+	perceptron.Weights = [][]float64{}
+	if err := perceptron.Fit(rows); err != nil {
+		t.Errorf("expected empty weights to be initialised, got %v", err)
+	}
+	perceptron.Weights = [][]float64{{0, 0, 0}, {0, 0}}
+	if err := perceptron.Fit(rows); err == nil {
+		t.Errorf("expected an error fitting a perceptron from ragged weights")
+	}
+	single_class := [][]float64{{0.1, -1, 0}, {0.2, -1, 0}, {0.3, -1, 0}, {0.4, -1, 0}, {0.5, -1, 0}}
+	if err := model.NewLinearSVM(5, 0.01, 1).Fit(single_class); err == nil {
+		t.Errorf("expected an error fitting a linear svm to a single class")
+	}
+	single_class[0][2] = -1
+	if err := model.NewLinearSVM(5, 0.01, 1).Fit(single_class); err == nil {
+		t.Errorf("expected an error fitting a linear svm to a negative class label")
+	}
+	if scores := model.NewDecisionTree(model.Gini, 5, 2, 1).PredictScores(rows[0][:3]); scores != nil {
+		t.Errorf("expected no scores from an unfitted decision tree, got %v", scores)
+	}
+	if label := model.NewDecisionTree(model.Gini, 5, 2, 1).Predict(rows[0][:3]); label != -1 {
+		t.Errorf("expected an unfitted decision tree to predict -1, got %d", label)
+	}
+	if label := model.NewRandomForest(10, model.Gini, 1).Predict(rows[0][:3]); label != -1 {
+		t.Errorf("expected an unfitted random forest to predict -1, got %d", label)
+	}
+
+	for name, new_classifier := range getClassifiers() {
+		classifier := new_classifier()
+		if label := classifier.Predict(rows[0][:3]); label != -1 {
+			t.Errorf("%s: expected an unfitted classifier to predict -1, got %d", name, label)
+		}
+		if successes, errors := model.Evaluate(classifier, rows); successes != 0 || errors != len(rows) {
+			t.Errorf("%s: expected every row to count as an error, got %d successes and %d errors", name, successes, errors)
+		}
+	}
+}
+
+// TestModelSaveLoad tests that a saved model keeps its pipeline, including
+// its binarization, and its classifier, whatever the classifier's type.
+func TestModelSaveLoad(t *testing.T) {
+	pipeline := model.DefaultPipeline()
+	pipeline.Binarization = helpers.Binarization{Method: helpers.Sauvola, Window: 15, K: 0.3}
+
+	classifier := model.NewGaussianNB()
+	if err := classifier.Fit(getClusteredRows(50, 4)); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "model.json")
+	if err := (model.Model{Pipeline: pipeline, Classifier: classifier}).Save(file); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := model.LoadModel(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Pipeline.Binarization != pipeline.Binarization {
+		t.Errorf("expected binarization %v, got %v", pipeline.Binarization, loaded.Pipeline.Binarization)
+	}
+	if _, ok := loaded.Classifier.(*model.GaussianNB); !ok {
+		t.Errorf("expected a *model.GaussianNB, got %T", loaded.Classifier)
+	}
+
+	for name, new_classifier := range getClassifiers() {
+		classifier := new_classifier()
+		if err := (model.Model{Pipeline: pipeline, Classifier: classifier}).Save(file); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		loaded, err := model.LoadModel(file)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if reflect.TypeOf(loaded.Classifier) != reflect.TypeOf(classifier) {
+			t.Errorf("%s: expected a %T, got %T", name, classifier, loaded.Classifier)
+		}
+	}
+}
+
+// TestFeatureCache tests that validation data read back from the feature
+// cache matches the data extracted from the images.
+func TestFeatureCache(t *testing.T) {
+	t.Chdir("..")
+
+	cache_directory := model.CacheDirectory
+	model.CacheDirectory = t.TempDir()
+	defer func() { model.CacheDirectory = cache_directory }()
+
+	extracted, err := model.GetValidationData(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache_files, err := filepath.Glob(filepath.Join(model.CacheDirectory, "*.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cache_files) != 10 {
+		t.Fatalf("expected one cache file per validation file, got %d", len(cache_files))
+	}
+
+	cached, err := model.GetValidationData(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both data sets are shuffled, so compare them as sets of rows.
+	counts := map[string]int{}
+	for i := range extracted {
+		counts[fmt.Sprint(extracted[i])]++
+		counts[fmt.Sprint(cached[i])]--
+	}
+	for row, count := range counts {
+		if count != 0 {
+			t.Fatalf("row %s differs between the extracted and cached data", row)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package testing_framework
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"project04_perceptron/go_rewrite/helpers"
+)
+
+// getImageRow returns a CSV row holding the provided label followed by 784
+// pixel values, all set to the provided value.
+func getImageRow(label, value string) string {
+	return label + strings.Repeat(","+value, 28*28)
+}
+
+// TestImageReader checks that images are read one row at a time and that
+// malformed rows are reported with their line and column.
+func TestImageReader(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "images.csv")
+	lines := []string{
+		"label,pixels",
+		getImageRow("3", "255"),
+		getImageRow("4", "0"),
+		"5,1,2",
+		"6" + strings.Repeat(",0", 24) + ",256" + strings.Repeat(",0", 28*28-25),
+	}
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := helpers.NewImageReader(file, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	for _, expected := range []struct{ label, value int }{{3, 255}, {4, 0}} {
+		image, label, err := reader.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if label != expected.label || image.Width != 28 || image.Height != 28 || image.At(27, 27) != expected.value {
+			t.Errorf("expected a 28x28 image of %d labelled %d, got %dx%d of %d labelled %d",
+				expected.value, expected.label, image.Width, image.Height, image.At(27, 27), label)
+		}
+	}
+
+	for _, expected := range []helpers.CSVError{{File: file, Line: 4, Column: 0}, {File: file, Line: 5, Column: 26}} {
+		_, _, err := reader.Next()
+		var csv_error *helpers.CSVError
+		if !errors.As(err, &csv_error) || csv_error.File != expected.File || csv_error.Line != expected.Line || csv_error.Column != expected.Column {
+			t.Errorf("expected an error at line %d, column %d, got %v", expected.Line, expected.Column, err)
+		}
+	}
+
+	if _, _, err := reader.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF after the last row, got %v", err)
+	}
+
+	if _, _, err := helpers.ExtractImages(file, true); err == nil || !strings.Contains(err.Error(), "images.csv:4:") {
+		t.Errorf("expected ExtractImages to report the short row, got %v", err)
+	}
+}
+
+// TestCSVSchema checks header detection, label columns found by index and by
+// name, pixel value scaling and the skipping of bad rows.
+func TestCSVSchema(t *testing.T) {
+	directory := t.TempDir()
+	pixels := strings.Repeat("0,", 3) + "0.5,1" // a 5x1 image from 0 to 1
+	files := map[string]string{
+		"headerless.csv": pixels + ",7\n" + pixels + ",x\n" + pixels + "\n" + pixels + ",8\n",
+		"named.csv":      "a,b,digit,c,d,e\n0,0,2,0,0.5,1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(directory, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	schema := helpers.CSVSchema{Header: helpers.HeaderDetect, LabelColumn: 5, Width: 5, Height: 1, MinValue: 0, MaxValue: 1, SkipBadRows: true}
+	images, labels, skipped, err := schema.ExtractImages(filepath.Join(directory, "headerless.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 2 || !reflect.DeepEqual(labels, []int{7, 8}) || !reflect.DeepEqual(images[1].Pixels, []int{0, 0, 0, 128, 255}) {
+		t.Errorf("expected two images labelled 7 and 8, got %d labelled %v: %v", len(images), labels, images)
+	}
+	if len(skipped) != 2 || skipped[0].Line != 2 || skipped[0].Column != 6 || skipped[1].Line != 3 || skipped[1].Column != 0 {
+		t.Errorf("expected the bad label on line 2 and the short row on line 3 to be skipped, got %v", skipped)
+	}
+
+	reader, err := schema.Open(filepath.Join(directory, "headerless.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	for _, expected := range []int{0, 3} {
+		if _, _, err := reader.Next(); err != nil || reader.Row() != expected {
+			t.Errorf("expected row %d, got %d (%v)", expected, reader.Row(), err)
+		}
+	}
+
+	schema.SkipBadRows = false
+	if _, _, _, err := schema.ExtractImages(filepath.Join(directory, "headerless.csv")); err == nil {
+		t.Errorf("expected an error for the bad label without SkipBadRows")
+	}
+
+	schema = helpers.CSVSchema{Header: helpers.HeaderPresent, LabelName: "digit", Width: 5, Height: 1, MinValue: 0, MaxValue: 1}
+	images, labels, _, err = schema.ExtractImages(filepath.Join(directory, "named.csv"))
+	if err != nil || !reflect.DeepEqual(labels, []int{2}) || !reflect.DeepEqual(images[0].Pixels, []int{0, 0, 0, 128, 255}) {
+		t.Errorf("expected an image labelled 2 from the named column, got %v, %v (%v)", images, labels, err)
+	}
+
+	schema.LabelName = "label"
+	if _, _, _, err := schema.ExtractImages(filepath.Join(directory, "named.csv")); err == nil {
+		t.Errorf("expected an error for a missing label column")
+	}
+}
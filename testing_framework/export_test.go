@@ -0,0 +1,79 @@
+package testing_framework
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"project04_perceptron/go_rewrite/model"
+)
+
+// TestExportImport checks that a data set written in each export format is
+// read back unchanged, and that an externally built CSV of feature values can
+// be trained on.
+func TestExportImport(t *testing.T) {
+	data_set := model.DataSet{FeatureNames: []string{"Density", "Max, Vertical 'Intersections'"}}
+	for i, row := range getClusteredRows(20, 1) {
+		data_set.Samples = append(data_set.Samples, model.Sample{
+			Source:    `input_files/a "quoted", file's name.csv`,
+			Row:       i / 2,
+			Augmented: i%2 == 1,
+			Label:     int(row[3]),
+			Features:  row[:2],
+		})
+	}
+	data_set.Samples[0].Features[0] = 1.0 / 3
+	data_set.Samples[1].Label = -1
+
+	for _, format := range []model.ExportFormat{model.CSV, model.JSONLines, model.ARFF} {
+		file := filepath.Join(t.TempDir(), "features."+string(format))
+		if detected, err := model.GetExportFormat(file); err != nil || detected != format {
+			t.Errorf("%s: expected format %s, got %s (%v)", file, format, detected, err)
+		}
+		if err := model.ExportDataSet(data_set, file, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		imported, err := model.ImportDataSet(file, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !reflect.DeepEqual(imported, data_set) {
+			t.Errorf("%s: round trip changed the data set:\n%+v\n%+v", format, data_set, imported)
+		}
+	}
+
+	if _, err := data_set.Rows(); err == nil {
+		t.Errorf("expected an error for the rows of an unlabelled sample")
+	}
+
+	file := filepath.Join(t.TempDir(), "external.csv")
+	external := "x,y,label\n"
+	for _, row := range getClusteredRows(40, 2) {
+		external += strconv.FormatFloat(row[0], 'g', -1, 64) + "," + strconv.FormatFloat(row[1], 'g', -1, 64) + "," + strconv.FormatFloat(row[3], 'g', -1, 64) + "\n"
+	}
+	if err := os.WriteFile(file, []byte(external), 0644); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := model.ImportDataSet(file, model.CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(imported.FeatureNames, []string{"x", "y"}) || imported.Samples[3].Source != file || imported.Samples[3].Row != 3 {
+		t.Errorf("unexpected import of an external CSV: %v, %+v", imported.FeatureNames, imported.Samples[3])
+	}
+
+	rows, err := imported.Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	weights := [][]float64{{0, 0, 0}, {0, 0, 0}}
+	_, _, errors, err := model.TrainOnData(weights, 5, rows, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errors > 5 {
+		t.Errorf("expected the clusters to be separated, got %d errors over 5 epochs", errors)
+	}
+}
@@ -4,6 +4,8 @@ package testing_framework
 
 import (
 	"fmt"
+	"math"
+	"reflect"
 	"testing"
 
 	"project04_perceptron/go_rewrite/feature_extraction"
@@ -48,3 +50,388 @@ func TestNumLoops(t *testing.T) {
 		}
 	}
 }
+
+// TestZoningDensities tests that GetZoningDensities splits an image into the
+// requested grid and that the registered zoning extractor names every cell.
+func TestZoningDensities(t *testing.T) {
+	// The left half of a 28x28 image is white.
+	image, err := helpers.NewImage(28, 28, helpers.Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row := 0; row < 28; row++ {
+		for col := 0; col < 14; col++ {
+			image.Set(row, col, 1)
+		}
+	}
+
+	densities := feature_extraction.GetZoningDensities(image, 4, 4)
+	expected := []float64{1, 1, 0, 0}
+	for i, density := range densities {
+		if density != expected[i%4] {
+			t.Errorf("cell %d: expected density %f, got %f", i, expected[i%4], density)
+		}
+	}
+
+	names, err := feature_extraction.GetFeatureSetNames([]string{"basic", "zoning_7x7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := feature_extraction.GetFeatureSetValues(image, image, []string{"basic", "zoning_7x7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 9+49 || len(values) != len(names) {
+		t.Errorf("expected %d names and values, got %d names and %d values", 9+49, len(names), len(values))
+	}
+}
+
+// TestFeatureCallOrder tests that computing features leaves the image
+// unchanged, so that the value of each feature does not depend on which
+// features were computed before it.
+func TestFeatureCallOrder(t *testing.T) {
+	images, _, err := helpers.ExtractImages("../input_files/training_data/handwritten_samples_8.csv", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, image := range images[:50] {
+		original := image.Copy()
+		binary_image := helpers.GetBlackWhite(image, 128)
+		if !reflect.DeepEqual(image, original) {
+			t.Fatalf("image %d: GetBlackWhite modified its input", i)
+		}
+
+		ud_symmetry, lr_symmetry := feature_extraction.GetUDSymmetry(binary_image), feature_extraction.GetLRSymmetry(binary_image)
+		num_loops, err := feature_extraction.GetNumLoops(binary_image)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		feature_values := feature_extraction.GetFeatureValues(binary_image)
+		if feature_values[6] != float64(num_loops) || feature_values[7] != ud_symmetry || feature_values[8] != lr_symmetry {
+			t.Errorf("image %d: expected loops and split symmetries %d, %f, %f, got %v",
+				i, num_loops, ud_symmetry, lr_symmetry, feature_values[6:])
+		}
+
+		forward, err := feature_extraction.GetFeatureSetValues(image, binary_image, []string{"basic", "topology", "moments"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		backward, err := feature_extraction.GetFeatureSetValues(image, binary_image, []string{"moments", "topology", "basic"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		num_moments, num_topology := len(forward)-9-8, 8
+		reordered := append(append(append([]float64{}, backward[num_moments+num_topology:]...),
+			backward[num_moments:num_moments+num_topology]...), backward[:num_moments]...)
+		if !reflect.DeepEqual(forward, reordered) {
+			t.Errorf("image %d: feature values depend on the order of the feature set", i)
+		}
+
+		if !reflect.DeepEqual(image, original) {
+			t.Errorf("image %d: computing features modified the greyscale image", i)
+		}
+	}
+}
+
+// isClose reports whether the provided values differ by at most tolerance.
+func isClose(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+// TestProjection tests the bucketed projection and projection statistics of
+// a vertical bar.
+func TestProjection(t *testing.T) {
+	// A bar three columns wide, in columns 10 to 12 and rows 4 to 23.
+	image, err := helpers.NewImage(28, 28, helpers.Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row := 4; row < 24; row++ {
+		for col := 10; col < 13; col++ {
+			image.Set(row, col, 1)
+		}
+	}
+
+	col_projection := feature_extraction.GetColumnProjection(image)
+	buckets := feature_extraction.GetBucketedProjection(col_projection, 28, 7)
+	expected_buckets := []float64{0, 0, 40.0 / 112, 20.0 / 112, 0, 0, 0}
+	for i := range expected_buckets {
+		if !isClose(buckets[i], expected_buckets[i], 1e-12) {
+			t.Errorf("column bucket %d: expected density %f, got %f", i, expected_buckets[i], buckets[i])
+		}
+	}
+
+	peak, mean, variance := feature_extraction.GetProjectionStatistics(col_projection)
+	if !isClose(peak, 10.0/28, 1e-12) || !isClose(mean, 11.0/28, 1e-12) || !isClose(variance, 2.0/3/784, 1e-12) {
+		t.Errorf("column projection: expected peak %f, mean %f and variance %f, got %f, %f and %f",
+			10.0/28, 11.0/28, 2.0/3/784, peak, mean, variance)
+	}
+
+	// The ink is spread evenly over rows 4 to 23.
+	peak, mean, variance = feature_extraction.GetProjectionStatistics(feature_extraction.GetRowProjection(image))
+	if !isClose(peak, 4.0/28, 1e-12) || !isClose(mean, 13.5/28, 1e-12) || !isClose(variance, (20.0*20-1)/12/784, 1e-12) {
+		t.Errorf("row projection: expected peak %f, mean %f and variance %f, got %f, %f and %f",
+			4.0/28, 13.5/28, (20.0*20-1)/12/784, peak, mean, variance)
+	}
+
+	peak, mean, variance = feature_extraction.GetProjectionStatistics(make([]int, 28))
+	if peak != 0 || mean != 0 || variance != 0 {
+		t.Errorf("blank projection: expected zero statistics, got %f, %f and %f", peak, mean, variance)
+	}
+}
+
+// TestMoments tests that Hu moments do not depend on the position or size of
+// a glyph, and the orientation and eccentricity of a bar and a blank image.
+func TestMoments(t *testing.T) {
+	glyph := []string{
+		"#....",
+		"#....",
+		"#..#.",
+		"#####",
+		"...#.",
+	}
+	// draw returns the glyph drawn on a blank 28x28 image with its top left
+	// corner at the provided position, each pixel scaled to a square of the
+	// provided size.
+	draw := func(top, left, scale int) helpers.Image {
+		image, err := helpers.NewImage(28, 28, helpers.Binary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for row, line := range glyph {
+			for col, char := range line {
+				for i := 0; char == '#' && i < scale*scale; i++ {
+					image.Set(top+row*scale+i/scale, left+col*scale+i%scale, 1)
+				}
+			}
+		}
+		return image
+	}
+
+	hu := feature_extraction.GetHuMoments(draw(2, 3, 1))
+	shifted := feature_extraction.GetHuMoments(draw(15, 20, 1))
+	scaled := feature_extraction.GetHuMoments(draw(5, 5, 3))
+	for i := range hu {
+		if !isClose(shifted[i], hu[i], 1e-12*math.Abs(hu[i])) {
+			t.Errorf("Hu moment %d: expected %g after shifting, got %g", i+1, hu[i], shifted[i])
+		}
+	}
+	// Scaling a pixelated glyph also changes the spread of each pixel, so the
+	// invariance only holds approximately.
+	for i := 0; i < 2; i++ {
+		if !isClose(scaled[i], hu[i], 0.1*math.Abs(hu[i])) {
+			t.Errorf("Hu moment %d: expected about %g after scaling, got %g", i+1, hu[i], scaled[i])
+		}
+	}
+
+	// A horizontal bar two rows high and twenty columns wide.
+	bar, err := helpers.NewImage(28, 28, helpers.Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row := 13; row < 15; row++ {
+		for col := 4; col < 24; col++ {
+			bar.Set(row, col, 1)
+		}
+	}
+	if orientation := feature_extraction.GetOrientation(bar); !isClose(orientation, 0, 1e-9) {
+		t.Errorf("horizontal bar: expected orientation 0, got %f", orientation)
+	}
+	if eccentricity := feature_extraction.GetEccentricity(bar); eccentricity < 0.99 || eccentricity > 1 {
+		t.Errorf("horizontal bar: expected eccentricity near 1, got %f", eccentricity)
+	}
+
+	blank, err := helpers.NewImage(28, 28, helpers.Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := feature_extraction.GetFeatureSetValues(blank, blank, []string{"moments"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hu := feature_extraction.GetHuMoments(blank); hu != [7]float64{} {
+		t.Errorf("blank image: expected zero Hu moments, got %v", hu)
+	}
+	if feature_extraction.GetOrientation(blank) != 0 || feature_extraction.GetEccentricity(blank) != 0 {
+		t.Errorf("blank image: expected zero orientation and eccentricity")
+	}
+	for i, value := range values {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			t.Errorf("blank image: moment feature %d is %f", i, value)
+		}
+	}
+}
+
+// TestHOG tests the length and block normalization of the HOG descriptor, and
+// that a vertical edge only produces horizontal gradients.
+func TestHOG(t *testing.T) {
+	// The right half of the image is bright, giving a vertical edge.
+	edge, err := helpers.NewImage(28, 28, helpers.Greyscale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row := 0; row < 28; row++ {
+		for col := 14; col < 28; col++ {
+			edge.Set(row, col, 255)
+		}
+	}
+	blank, err := helpers.NewImage(28, 28, helpers.Greyscale)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 3x3 blocks of 2x2 cells with 9 bins each.
+	values, err := feature_extraction.GetFeatureSetValues(edge, edge, []string{"hog"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 324 {
+		t.Errorf("expected 324 HOG values, got %d", len(values))
+	}
+
+	block_length := 2 * 2 * 9
+	for name, image := range map[string]helpers.Image{"edge": edge, "blank": blank} {
+		descriptor := feature_extraction.GetHOG(image, 7, 2, 9, feature_extraction.L2)
+		for block := 0; block*block_length < len(descriptor); block++ {
+			sum := 0.0
+			for _, value := range descriptor[block*block_length : (block+1)*block_length] {
+				sum += value * value
+			}
+			if (name == "edge" && !isClose(sum, 1, 1e-6)) || (name == "blank" && sum != 0) {
+				t.Errorf("%s: block %d has squared norm %f", name, block, sum)
+			}
+		}
+	}
+
+	// A horizontal gradient has an angle of 0, which lies between the centres
+	// of the first and last bins.
+	descriptor := feature_extraction.GetHOG(edge, 7, 2, 9, feature_extraction.L2)
+	for i, value := range descriptor {
+		if bin := i % 9; bin != 0 && bin != 8 && value != 0 {
+			t.Errorf("value %d is in bin %d, expected only bins 0 and 8", i, bin)
+			break
+		}
+	}
+}
+
+// getGlyph returns a binary image drawn by the provided rows, in which '#'
+// marks ink and any other character background.
+func getGlyph(t *testing.T, rows []string) helpers.Image {
+	t.Helper()
+	image, err := helpers.NewImage(len(rows[0]), len(rows), helpers.Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row, line := range rows {
+		for col, char := range line {
+			if char == '#' {
+				image.Set(row, col, 1)
+			}
+		}
+	}
+	return image
+}
+
+// TestTopology tests the Euler number, holes, hole placement and ink
+// components of hand-drawn digits, which tell a 0, a 6 and a 9 apart.
+func TestTopology(t *testing.T) {
+	glyphs := []struct {
+		name         string
+		rows         []string
+		euler, holes int
+		placement    [3]int // holes in the upper, middle and lower third
+		components   int
+	}{
+		{"0", []string{
+			".........",
+			"..#####..",
+			".##...##.",
+			".#.....#.",
+			".#.....#.",
+			".#.....#.",
+			".#.....#.",
+			".##...##.",
+			"..#####..",
+			".........",
+		}, 0, 1, [3]int{0, 1, 0}, 1},
+		{"8", []string{
+			".........",
+			"..#####..",
+			"..#...#..",
+			"..#...#..",
+			"..#####..",
+			".#.....#.",
+			".#.....#.",
+			".#.....#.",
+			".#######.",
+			".........",
+		}, -1, 2, [3]int{1, 0, 1}, 1},
+		{"6", []string{
+			".........",
+			"....##...",
+			"...#.....",
+			"..#......",
+			"..#......",
+			"..#####..",
+			"..#...#..",
+			"..#...#..",
+			"..#####..",
+			".........",
+		}, 0, 1, [3]int{0, 0, 1}, 1},
+		{"9", []string{
+			".........",
+			"..#####..",
+			"..#...#..",
+			"..#...#..",
+			"..#####..",
+			"......#..",
+			"......#..",
+			".....#...",
+			"...##....",
+			".........",
+		}, 0, 1, [3]int{1, 0, 0}, 1},
+		{"=", []string{
+			".........",
+			".........",
+			".#######.",
+			".........",
+			".........",
+			".#######.",
+			".........",
+		}, 2, 0, [3]int{}, 2},
+	}
+
+	placements := map[string][3]int{}
+	for _, glyph := range glyphs {
+		image := getGlyph(t, glyph.rows)
+
+		if euler := feature_extraction.GetEulerNumber(image); euler != glyph.euler {
+			t.Errorf("%s: expected Euler number %d, got %d", glyph.name, glyph.euler, euler)
+		}
+		if holes := feature_extraction.GetHoles(image); len(holes) != glyph.holes {
+			t.Errorf("%s: expected %d holes, got %d", glyph.name, glyph.holes, len(holes))
+		}
+		if components := feature_extraction.GetInkComponents(image); components != glyph.components {
+			t.Errorf("%s: expected %d ink components, got %d", glyph.name, glyph.components, components)
+		}
+
+		// The third to fifth topology features count the holes in the
+		// upper, middle and lower thirds.
+		values, err := feature_extraction.GetFeatureSetValues(image, image, []string{"topology"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		placement := [3]int{int(values[2]), int(values[3]), int(values[4])}
+		if placement != glyph.placement {
+			t.Errorf("%s: expected %v holes in the upper, middle and lower thirds, got %v", glyph.name, glyph.placement, placement)
+		}
+		placements[glyph.name] = placement
+	}
+
+	// The single holes of a 0, a 6 and a 9 are all placed differently.
+	if zero, six, nine := placements["0"], placements["6"], placements["9"]; zero == six || zero == nine || six == nine {
+		t.Errorf("expected 0, 6 and 9 to place their holes differently, got %v, %v and %v", zero, six, nine)
+	}
+}
@@ -0,0 +1,432 @@
+package testing_framework
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"project04_perceptron/go_rewrite/feature_extraction"
+	"project04_perceptron/go_rewrite/helpers"
+	"project04_perceptron/go_rewrite/img_manip"
+	"project04_perceptron/go_rewrite/model"
+)
+
+// TestMorphology tests that closing bridges a one pixel break in a stroke, that
+// opening removes an isolated speck of ink, and that ApplyMorphology applies
+// each step by its operation.
+func TestMorphology(t *testing.T) {
+	square, err := img_manip.NewStructuringElement(img_manip.Square, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cross, err := img_manip.NewStructuringElement(img_manip.Cross, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	broken := getImage(t, [][]int{
+		{0, 0, 0, 0, 0, 0, 0},
+		{0, 1, 1, 0, 1, 1, 0},
+		{0, 0, 0, 0, 0, 0, 0},
+	}, helpers.Binary)
+	if closed := img_manip.Close(broken, square); closed.At(1, 3) != 1 {
+		t.Errorf("expected closing to bridge the break, got %v", closed.Matrix()[1])
+	}
+
+	speckled := getImage(t, [][]int{
+		{0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0},
+		{0, 0, 1, 0, 0},
+		{0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0},
+	}, helpers.Binary)
+	if opened := img_manip.Open(speckled, cross); opened.At(2, 2) != 0 {
+		t.Errorf("expected opening to remove the speck")
+	}
+
+	steps := []img_manip.MorphologyStep{{Operation: img_manip.CloseOperation, Shape: img_manip.Square, Size: 3}}
+	if closed, err := img_manip.ApplyMorphology(broken, steps); err != nil || !reflect.DeepEqual(closed, img_manip.Close(broken, square)) {
+		t.Errorf("expected a close step to match Close, got error %v", err)
+	}
+	steps[0].Operation = "thicken"
+	if _, err := img_manip.ApplyMorphology(broken, steps); err == nil {
+		t.Errorf("expected an error for an unknown morphological operation")
+	}
+}
+
+// TestAffine tests that rotating by 90 degrees matches Rotate90 on an image
+// that is not 28x28, and that a transform followed by its inverse is the
+// identity.
+func TestAffine(t *testing.T) {
+	image := getImage(t, [][]int{
+		{1, 2, 3},
+		{4, 5, 6},
+		{7, 8, 9},
+	}, helpers.Greyscale)
+
+	rotated, err := img_manip.Rotate(image, 90, img_manip.NearestNeighbour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := img_manip.Rotate90(image, 1)
+	if !reflect.DeepEqual(rotated, expected) {
+		t.Fatalf("expected %v, got %v", expected.Matrix(), rotated.Matrix())
+	}
+
+	transform := img_manip.Rotation(17, 1, 1).Then(img_manip.Shear(0.3, 1, 1)).Then(img_manip.Translation(2, -1))
+	inverse, err := transform.Invert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	col, row := transform.Then(inverse).Apply(5, 7)
+	if math.Abs(col-5) > 1e-9 || math.Abs(row-7) > 1e-9 {
+		t.Errorf("expected (5, 7), got (%f, %f)", col, row)
+	}
+}
+
+// TestBinarize tests that Otsu's method and Sauvola's method both keep a faint
+// stroke on a faint background that a fixed threshold of 128 loses, and that
+// the mean threshold of an image with no pixels is the default.
+func TestBinarize(t *testing.T) {
+	image, err := helpers.NewImage(15, 15, helpers.Greyscale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row := 0; row < image.Height; row++ {
+		for col := 0; col < image.Width; col++ {
+			image.Set(row, col, 20)
+			if col == 7 {
+				image.Set(row, col, 90)
+			}
+		}
+	}
+
+	for _, binarization := range []helpers.Binarization{
+		{Method: helpers.Otsu},
+		{Method: helpers.Sauvola, Window: 7, K: 0.3},
+	} {
+		binary, err := helpers.Binarize(image, binarization)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if binary.At(7, 7) != 1 || binary.At(7, 3) != 0 {
+			t.Errorf("%s: expected the stroke to be kept and the background dropped, got %v", binarization.Method, binary.Matrix()[7])
+		}
+	}
+
+	fixed, err := helpers.Binarize(image, helpers.Binarization{Method: helpers.Fixed, Threshold: 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed.At(7, 7) != 0 {
+		t.Errorf("expected a fixed threshold of 128 to lose the stroke")
+	}
+	if image.At(7, 7) != 90 {
+		t.Errorf("expected Binarize not to modify its input")
+	}
+	if threshold := helpers.GetMeanThreshold(helpers.Image{Depth: helpers.Greyscale}); threshold != 128 {
+		t.Errorf("expected an image with no pixels to have the default threshold of 128, got %d", threshold)
+	}
+}
+
+// TestImage tests that images of any size can be flipped, rotated and reduced
+// to features, and that out-of-range rows and pixels are rejected.
+func TestImage(t *testing.T) {
+	image, err := helpers.NewImage(40, 30, helpers.Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row := 5; row < 25; row++ {
+		image.Set(row, 9, 1)
+		image.Set(row, 30, 1)
+	}
+
+	if feature_values := feature_extraction.GetFeatureValues(image); feature_values[1] != 0 {
+		t.Errorf("expected a symmetric 40x30 image to have vertical symmetry 0, got %f", feature_values[1])
+	}
+
+	image.Set(0, 0, 1)
+	if flipped := img_manip.FlipLR(image); flipped.At(0, 39) != 1 || flipped.At(0, 0) != 0 {
+		t.Errorf("expected FlipLR to mirror the columns of a 40x30 image")
+	}
+	if rotated := img_manip.Rotate90(image, 1); rotated.Width != 30 || rotated.Height != 40 || rotated.At(0, 29) != 1 {
+		t.Errorf("expected Rotate90 to turn a 40x30 image into a 30x40 image")
+	}
+	if image.At(-1, 0) != 0 || image.At(0, 40) != 0 {
+		t.Errorf("expected pixels outside the image to be background")
+	}
+
+	if _, err := helpers.NewImageFromMatrix([][]int{{0, 1}, {1}}, helpers.Binary); err == nil {
+		t.Errorf("expected an error for a ragged matrix")
+	}
+	if _, err := helpers.NewImageFromMatrix([][]int{{0, 2}}, helpers.Binary); err == nil {
+		t.Errorf("expected an error for a binary pixel of 2")
+	}
+}
+
+// TestThinning tests that both thinning methods reduce a thick bar and a plus
+// sign to a connected skeleton one pixel wide, that a line has two end points
+// and a T one branch point, and that an unknown method is rejected.
+func TestThinning(t *testing.T) {
+	bar, err := helpers.NewImage(20, 9, helpers.Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plus, err := helpers.NewImage(15, 15, helpers.Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row := 2; row < 7; row++ {
+		for col := 2; col < 18; col++ {
+			bar.Set(row, col, 1)
+		}
+	}
+	for i := 1; i < 14; i++ {
+		for j := 6; j < 9; j++ {
+			plus.Set(i, j, 1)
+			plus.Set(j, i, 1)
+		}
+	}
+
+	for _, method := range []img_manip.ThinningMethod{img_manip.ZhangSuen, img_manip.GuoHall} {
+		for name, image := range map[string]helpers.Image{"bar": bar, "plus": plus} {
+			skeleton, err := img_manip.Thin(image, method)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img_manip.GetSkeletonLength(skeleton) == 0 {
+				t.Errorf("%s: expected the %s to keep a skeleton", method, name)
+				continue
+			}
+			if components := feature_extraction.GetInkComponents(skeleton); components != 1 {
+				t.Errorf("%s: expected the skeleton of the %s to be connected, got %d components", method, name, components)
+			}
+			for row := 0; row < skeleton.Height-1; row++ {
+				for col := 0; col < skeleton.Width-1; col++ {
+					if skeleton.At(row, col)+skeleton.At(row+1, col)+skeleton.At(row, col+1)+skeleton.At(row+1, col+1) == 4 {
+						t.Errorf("%s: expected the skeleton of the %s to be one pixel wide, got a 2x2 block at (%d, %d)", method, name, row, col)
+					}
+				}
+			}
+		}
+	}
+
+	line := getImage(t, [][]int{
+		{0, 0, 0, 0, 0, 0, 0},
+		{0, 1, 1, 1, 1, 1, 0},
+		{0, 0, 0, 0, 0, 0, 0},
+	}, helpers.Binary)
+	if end_points := img_manip.GetEndPoints(line); len(end_points) != 2 {
+		t.Errorf("expected a line to have 2 end points, got %v", end_points)
+	}
+
+	tee := getImage(t, [][]int{
+		{0, 0, 0, 0, 0, 0, 0},
+		{0, 1, 1, 1, 1, 1, 0},
+		{0, 0, 0, 1, 0, 0, 0},
+		{0, 0, 0, 1, 0, 0, 0},
+		{0, 0, 0, 1, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0},
+	}, helpers.Binary)
+	if branch_points := img_manip.GetBranchPoints(tee); len(branch_points) != 1 {
+		t.Errorf("expected a T to have 1 branch point, got %v", branch_points)
+	}
+
+	if _, err := img_manip.Thin(line, "unknown"); err == nil {
+		t.Errorf("expected an error for an unknown thinning method")
+	}
+}
+
+// TestNormalize tests that an off-centre digit is centred by its centre of
+// mass, that a slanted bar is deskewed upright, that FitToBox keeps the aspect
+// ratio and keeps a tiny glyph within the box, and that a blank image is
+// returned unchanged.
+func TestNormalize(t *testing.T) {
+	off_centre, err := helpers.NewImage(28, 28, helpers.Greyscale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row := 2; row < 7; row++ {
+		for col := 3; col < 8; col++ {
+			off_centre.Set(row, col, 255)
+		}
+	}
+	centred, err := img_manip.CenterByMass(off_centre, img_manip.NearestNeighbour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row, col, _ := getMassStatistics(centred); math.Abs(row-13.5) > 0.5 || math.Abs(col-13.5) > 0.5 {
+		t.Errorf("expected the centre of mass to move to (13.5, 13.5), got (%f, %f)", row, col)
+	}
+
+	slanted, err := helpers.NewImage(28, 28, helpers.Greyscale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row := 4; row < 24; row++ {
+		col := 10 + (row-4)/3
+		slanted.Set(row, col, 255)
+		slanted.Set(row, col+1, 255)
+	}
+	deskewed, err := img_manip.Deskew(slanted, img_manip.Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, slant := getMassStatistics(slanted); math.Abs(slant) < 0.2 {
+		t.Fatalf("expected the bar to start slanted, got a slant of %f", slant)
+	}
+	if _, _, slant := getMassStatistics(deskewed); math.Abs(slant) > 0.05 {
+		t.Errorf("expected the deskewed bar to be upright, got a slant of %f", slant)
+	}
+
+	tall, err := helpers.NewImage(4, 8, helpers.Greyscale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range tall.Pixels {
+		tall.Pixels[i] = 255
+	}
+	fitted, err := img_manip.FitToBox(tall, 20, 28, 28, img_manip.NearestNeighbour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	top, left, bottom, right, ok := img_manip.GetBoundingBox(fitted)
+	if height, width := bottom-top+1, right-left+1; !ok || math.Abs(float64(height)-20) > 1 || math.Abs(float64(width)-10) > 1 {
+		t.Errorf("expected a 4x8 image to fit a 10x20 box, got %dx%d", width, height)
+	}
+
+	dot, err := helpers.NewImage(1, 1, helpers.Greyscale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dot.Set(0, 0, 255)
+	fitted, err = img_manip.FitToBox(dot, 20, 28, 28, img_manip.Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	top, left, bottom, right, ok = img_manip.GetBoundingBox(fitted)
+	if height, width := bottom-top+1, right-left+1; !ok || height != 20 || width != 20 {
+		t.Errorf("expected a single pixel to fill a 20x20 box, got %dx%d", width, height)
+	}
+
+	blank, err := helpers.NewImage(28, 28, helpers.Greyscale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	normalized, err := img_manip.Normalize(blank, img_manip.Normalization{Deskew: true, BoxSize: 20, Center: true, Interpolation: img_manip.Bilinear})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(normalized, blank) {
+		t.Errorf("expected a blank image to be returned unchanged")
+	}
+}
+
+// TestAugment tests that augmentation is repeatable from its seed, keeps
+// every pixel in the greyscale range, and adds Multiplier copies of each
+// training image with its class label.
+func TestAugment(t *testing.T) {
+	image, err := helpers.NewImage(28, 28, helpers.Greyscale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row := 4; row < 24; row++ {
+		image.Set(row, 13, 255)
+		image.Set(row, 14, 200)
+	}
+
+	augmentation := img_manip.DefaultAugmentation(1, 7)
+	augmentation.StrokeProbability, augmentation.NoiseProbability = 0.5, 0.2
+	first, second := rand.New(rand.NewSource(augmentation.Seed)), rand.New(rand.NewSource(augmentation.Seed))
+	for i := 0; i < 20; i++ {
+		augmented, err := img_manip.Augment(image, augmentation, first)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repeated, err := img_manip.Augment(image, augmentation, second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(augmented, repeated) {
+			t.Fatalf("expected the same seed to give the same augmented image")
+		}
+		for _, pixel := range augmented.Pixels {
+			if pixel < 0 || pixel > 255 {
+				t.Fatalf("expected augmented pixels between 0 and 255, got %d", pixel)
+			}
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "images.csv")
+	lines := []string{"label,pixels", getImageRow("3", "255"), getImageRow("4", "0"), getImageRow("7", "128")}
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache_directory := model.CacheDirectory
+	model.CacheDirectory = ""
+	defer func() { model.CacheDirectory = cache_directory }()
+
+	pipeline := model.DefaultPipeline()
+	pipeline.Augmentation = &augmentation
+	augmentation.Multiplier = 2
+	data_set, err := pipeline.GetDataSet([]string{file}, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data_set.Samples) != (1+augmentation.Multiplier)*3 {
+		t.Fatalf("expected %d samples, got %d", (1+augmentation.Multiplier)*3, len(data_set.Samples))
+	}
+	labels, num_augmented := []int{3, 4, 7}, 0
+	for _, sample := range data_set.Samples {
+		if sample.Label != labels[sample.Row] {
+			t.Errorf("expected sample of row %d to be labelled %d, got %d", sample.Row, labels[sample.Row], sample.Label)
+		}
+		if sample.Augmented {
+			num_augmented++
+		}
+	}
+	if num_augmented != augmentation.Multiplier*3 {
+		t.Errorf("expected %d augmented samples, got %d", augmentation.Multiplier*3, num_augmented)
+	}
+}
+
+// getMassStatistics returns the row and column of the centre of mass of the
+// provided image and its slant, mu11 / mu02, as estimated by Deskew.
+func getMassStatistics(image helpers.Image) (float64, float64, float64) {
+	mass, row_sum, col_sum := 0.0, 0.0, 0.0
+	for row := 0; row < image.Height; row++ {
+		for col := 0; col < image.Width; col++ {
+			value := float64(image.At(row, col))
+			mass += value
+			row_sum += value * float64(row)
+			col_sum += value * float64(col)
+		}
+	}
+	mass_row, mass_col := row_sum/mass, col_sum/mass
+
+	mu11, mu02 := 0.0, 0.0
+	for row := 0; row < image.Height; row++ {
+		for col := 0; col < image.Width; col++ {
+			value := float64(image.At(row, col))
+			mu11 += value * (float64(col) - mass_col) * (float64(row) - mass_row)
+			mu02 += value * (float64(row) - mass_row) * (float64(row) - mass_row)
+		}
+	}
+	return mass_row, mass_col, mu11 / mu02
+}
+
+// getImage returns an image of the provided depth holding the provided
+// matrix, failing the test if the matrix is not a valid image.
+func getImage(t *testing.T, matrix [][]int, depth helpers.Depth) helpers.Image {
+	t.Helper()
+	image, err := helpers.NewImageFromMatrix(matrix, depth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return image
+}
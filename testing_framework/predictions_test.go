@@ -0,0 +1,203 @@
+package testing_framework
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"project04_perceptron/go_rewrite/model"
+)
+
+// TestPredictionWriters checks that scored predictions agree with Predict,
+// identify their input rows, and are written in each prediction format.
+func TestPredictionWriters(t *testing.T) {
+	test_file := "../input_files/testing_data/unlabeled_digits.csv"
+	pipeline := model.DefaultPipeline()
+
+	training_rows, err := pipeline.GetTestingData(test_file, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Label the testing rows arbitrarily to fit a classifier whose vote shares
+	// are its scores.
+	for i := range training_rows {
+		training_rows[i] = append(training_rows[i], float64(i%3))
+	}
+	classifier := model.NewKNN(5, model.Euclidean, true)
+	if err := classifier.Fit(training_rows); err != nil {
+		t.Fatal(err)
+	}
+
+	predictions, err := pipeline.GetScoredPredictions(test_file, classifier, 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(predictions) != len(training_rows) {
+		t.Fatalf("expected %d predictions, got %d", len(training_rows), len(predictions))
+	}
+	for i, prediction := range predictions {
+		if prediction.Source != test_file || prediction.Row != i {
+			t.Errorf("prediction %d is for row %d of %s", i, prediction.Row, prediction.Source)
+		}
+		if expected := classifier.Predict(training_rows[i][:len(training_rows[i])-1]); prediction.Label != expected {
+			t.Errorf("row %d: expected label %d, got %d", i, expected, prediction.Label)
+		}
+		if len(prediction.TopK) != 2 || prediction.TopK[0] != prediction.ClassScore || prediction.TopK[1].Score > prediction.Score {
+			t.Errorf("row %d: top k %v does not start with the prediction %v", i, prediction.TopK, prediction.ClassScore)
+		}
+		if prediction.Confidence != prediction.Score {
+			t.Errorf("row %d: expected the share of the vote as the confidence, got %v for %v", i, prediction.Confidence, prediction.Score)
+		}
+	}
+
+	directory := t.TempDir()
+	for _, format := range []model.ExportFormat{model.CSV, model.JSONLines, model.Submission} {
+		if err := model.WritePredictions(predictions, filepath.Join(directory, string(format)), format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+	}
+	if err := model.WritePredictions(predictions, filepath.Join(directory, "arff"), model.ARFF); err == nil {
+		t.Errorf("expected an error writing predictions as ARFF")
+	}
+
+	for format, expected_header := range map[model.ExportFormat][]string{
+		model.CSV:        {"source", "row", "label", "score", "confidence", "top1_label", "top1_confidence", "top2_label", "top2_confidence"},
+		model.Submission: {"ImageId", "Label"},
+	} {
+		csv_file, err := os.Open(filepath.Join(directory, string(format)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(csv_file).ReadAll()
+		csv_file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(records[0], expected_header) || len(records) != len(predictions)+1 {
+			t.Errorf("%s: unexpected header %v or %d records", format, records[0], len(records))
+		}
+	}
+
+	jsonl_file, err := os.Open(filepath.Join(directory, string(model.JSONLines)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jsonl_file.Close()
+	scanner := bufio.NewScanner(jsonl_file)
+	for i := 0; scanner.Scan(); i++ {
+		var record struct {
+			Row   int `json:"row"`
+			Label int `json:"label"`
+			TopK  []struct {
+				Label int `json:"label"`
+			} `json:"top_k"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Row != predictions[i].Row || record.Label != predictions[i].Label || len(record.TopK) != 2 {
+			t.Errorf("line %d: unexpected record %+v", i+1, record)
+		}
+	}
+}
+
+// TestNonFiniteScores checks that a class scoring -Inf, as a class without a
+// centroid does, ranks last with a confidence of 0 while the other confidences
+// stay finite, and that its predictions can be written as JSON Lines.
+func TestNonFiniteScores(t *testing.T) {
+	test_file := "../input_files/testing_data/unlabeled_digits.csv"
+	pipeline := model.DefaultPipeline()
+
+	training_rows, err := pipeline.GetTestingData(test_file, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Label the rows 0 and 2 only, so that class 1 has no centroid.
+	for i := range training_rows {
+		training_rows[i] = append(training_rows[i], float64(2*(i%2)))
+	}
+	classifier := model.NewNearestCentroid(model.Euclidean)
+	if err := classifier.Fit(training_rows); err != nil {
+		t.Fatal(err)
+	}
+
+	predictions, err := pipeline.GetScoredPredictions(test_file, classifier, 3, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, prediction := range predictions {
+		last := prediction.TopK[len(prediction.TopK)-1]
+		if last.Label != 1 || !math.IsInf(last.Score, -1) || last.Confidence != 0 {
+			t.Fatalf("row %d: expected class 1 last with a score of -Inf and no confidence, got %+v", i, last)
+		}
+		total := 0.0
+		for _, class_score := range prediction.TopK {
+			if math.IsNaN(class_score.Confidence) || math.IsInf(class_score.Confidence, 0) {
+				t.Fatalf("row %d: expected finite confidences, got %+v", i, prediction.TopK)
+			}
+			total += class_score.Confidence
+		}
+		if math.Abs(total-1) > 1e-9 {
+			t.Fatalf("row %d: expected the confidences to sum to 1, got %v", i, total)
+		}
+	}
+
+	if err := model.WritePredictions(predictions, filepath.Join(t.TempDir(), "predictions.jsonl"), model.JSONLines); err != nil {
+		t.Errorf("expected predictions with a -Inf score to be written as JSON Lines, got %v", err)
+	}
+}
+
+// TestDeclaredProbabilities checks that confidences follow the classifier's
+// declaration of its scores: logits that happen to look like probabilities are
+// still turned into confidences, while a classifier whose scores are
+// probabilities keeps them as its confidences.
+func TestDeclaredProbabilities(t *testing.T) {
+	test_file := "../input_files/testing_data/unlabeled_digits.csv"
+	pipeline := model.DefaultPipeline()
+
+	testing_rows, err := pipeline.GetTestingData(test_file, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// With the threshold value (-1) as the last feature, class 0 has a logit
+	// of 0 and class 1 a logit of 1 for every row.
+	num_features := len(testing_rows[0])
+	perceptron := model.NewPerceptron(1, 0.08, 1)
+	perceptron.Weights = [][]float64{make([]float64, num_features), make([]float64, num_features)}
+	perceptron.Weights[1][num_features-1] = -1
+
+	predictions, err := pipeline.GetScoredPredictions(test_file, perceptron, 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := math.E / (1 + math.E)
+	for i, prediction := range predictions {
+		if prediction.Label != 1 || prediction.Score != 1 || math.Abs(prediction.Confidence-expected) > 1e-12 {
+			t.Fatalf("row %d: expected label 1 with a score of 1 and a confidence of %f, got %+v", i, expected, prediction.ClassScore)
+		}
+	}
+
+	for _, test := range []struct {
+		classifier    model.Classifier
+		probabilities bool
+	}{
+		{model.NewKNN(5, model.Euclidean, false), true},
+		{model.NewEnsemble(3, 1, model.ScoreAverage, 1), true},
+		{model.NewEnsemble(3, 1, model.Stacking, 1), false},
+		{model.NewPerceptron(1, 0.08, 1), false},
+		{model.NewLinearSVM(1, 0.01, 1), false},
+	} {
+		probabilities := false
+		if probability_classifier, ok := test.classifier.(model.ProbabilityClassifier); ok {
+			probabilities = probability_classifier.Probabilities()
+		}
+		if probabilities != test.probabilities {
+			t.Errorf("%T: expected probabilities %t, got %t", test.classifier, test.probabilities, probabilities)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package testing_framework
+
+import (
+	"math/rand"
+	"testing"
+
+	"project04_perceptron/go_rewrite/helpers"
+	"project04_perceptron/go_rewrite/model"
+)
+
+// TestMatrix tests that MulVec computes the same logits as one dot product
+// per weight vector, that Axpy matches the allocating vector functions and
+// that CheckedDotProduct rejects vectors of unequal length.
+func TestMatrix(t *testing.T) {
+	weight_vectors := helpers.GetRandomWeights(10, 12)
+	weights, err := helpers.NewMatrixFromRows(weight_vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	features := helpers.GetRandomWeights(1, 12)[0]
+	logits := weights.MulVec(features, nil)
+	for i, weight_vector := range weight_vectors {
+		if expected := helpers.DotProduct(weight_vector, features); logits[i] != expected {
+			t.Errorf("logit %d: expected %f, got %f", i, expected, logits[i])
+		}
+	}
+
+	expected := helpers.AddVectors(weight_vectors[3], helpers.Multiply(features, 0.5))
+	helpers.Axpy(0.5, features, weights.Row(3))
+	for j := range expected {
+		if weights.At(3, j) != expected[j] {
+			t.Fatalf("expected %v, got %v", expected, weights.Row(3))
+		}
+	}
+
+	if _, err := helpers.CheckedDotProduct(features, features[1:]); err == nil {
+		t.Errorf("expected an error for vectors of unequal length")
+	}
+	if _, err := helpers.NewMatrixFromRows([][]float64{{1, 2}, {3}}); err == nil {
+		t.Errorf("expected an error for rows of unequal length")
+	}
+}
+
+// getUpdateBenchmark returns a 10x785 weight matrix, as used on raw pixels,
+// and rows of random feature values and labels to update it with.
+func getUpdateBenchmark() ([][]float64, [][]float64, []int) {
+	random := rand.New(rand.NewSource(1))
+	weight_vectors := helpers.GetRandomWeights(10, 785)
+
+	rows, labels := make([][]float64, 256), make([]int, 256)
+	for i := range rows {
+		rows[i] = make([]float64, 785)
+		for j := range rows[i] {
+			rows[i][j] = random.Float64()
+		}
+		labels[i] = random.Intn(10)
+	}
+	return weight_vectors, rows, labels
+}
+
+// BenchmarkUpdateAllocating measures the perceptron update written with the
+// allocating vector functions, which make four new slices per mistake.
+func BenchmarkUpdateAllocating(b *testing.B) {
+	weight_vectors, rows, labels := getUpdateBenchmark()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		features, label := rows[n%len(rows)], labels[n%len(rows)]
+
+		logits := make([]float64, len(weight_vectors))
+		for i := range weight_vectors {
+			logits[i] = helpers.DotProduct(weight_vectors[i], features)
+		}
+		if predicted := helpers.ArgMax(logits); predicted != label {
+			adjusted := helpers.Multiply(features, 0.08)
+			weight_vectors[predicted] = helpers.SubtractVectors(weight_vectors[predicted], adjusted)
+			weight_vectors[label] = helpers.AddVectors(weight_vectors[label], adjusted)
+		}
+	}
+}
+
+// BenchmarkPerceptronFit measures a full training run of the perceptron on
+// 1000 rows of two clusters.
+func BenchmarkPerceptronFit(b *testing.B) {
+	rows := getClusteredRows(1000, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if err := model.NewPerceptron(10, 0.08, 1).Fit(rows); err != nil {
+			b.Fatal(err)
+		}
+	}
+}